@@ -0,0 +1,201 @@
+package dynu
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+const defaultChallengeTTL = 60 * time.Second
+
+// ACMEChallenge solves ACME DNS-01 challenges with a Provider. Present and
+// CleanUp implement lego's challenge.Provider interface, and Timeout its
+// challenge.ProviderTimeout interface.
+//
+// Unlike the libdns methods of Provider, the record is created in the Dynu
+// domain the challenge hostname belongs to, found with GetRootDomain, so one
+// ACMEChallenge serves all domains of an account.
+type ACMEChallenge struct {
+	Provider *Provider
+	// TTL of the challenge records; 60 seconds by default.
+	TTL time.Duration
+	// Propagation waits until the authoritative nameservers serve a created
+	// record; a PropagationChecker with default settings if nil.
+	Propagation *PropagationChecker
+	// SkipPropagation returns from Present as soon as the record is created.
+	SkipPropagation bool
+
+	mutex   sync.Mutex
+	records map[challengeKey]challengeRecord
+}
+
+type challengeKey struct {
+	fqdn  string
+	value string
+}
+
+// challengeRecord is a TXT record created by Present
+type challengeRecord struct {
+	domainID int64
+	zone     string
+	record   libdns.Record
+}
+
+// ChallengeRecord returns the name and TXT value of the DNS-01 challenge
+// record of a domain, as defined in RFC 8555 section 8.4.
+func ChallengeRecord(domain, keyAuth string) (fqdn, value string) {
+	domain = strings.TrimPrefix(zoneToFqdn(domain), "*.")
+	digest := sha256.Sum256([]byte(keyAuth))
+	return "_acme-challenge." + domain, base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// Present creates the challenge record of the domain and waits until it has
+// propagated.
+func (a *ACMEChallenge) Present(domain, token, keyAuth string) error {
+	return a.PresentContext(context.Background(), domain, token, keyAuth)
+}
+
+// CleanUp deletes the challenge record created by Present; there is nothing
+// to do if Present failed.
+func (a *ACMEChallenge) CleanUp(domain, token, keyAuth string) error {
+	return a.CleanUpContext(context.Background(), domain, token, keyAuth)
+}
+
+// Timeout returns how long to wait for propagation and the interval of the
+// checks, as used by Present.
+func (a *ACMEChallenge) Timeout() (timeout, interval time.Duration) {
+	checker := a.propagation()
+	timeout, interval = checker.Timeout, checker.Interval
+	if timeout <= 0 {
+		timeout = defaultPropagationTimeout
+	}
+	if interval <= 0 {
+		interval = defaultPropagationInterval
+	}
+	return timeout, interval
+}
+
+// PresentContext is Present with a context.
+func (a *ACMEChallenge) PresentContext(ctx context.Context, domain, _, keyAuth string) error {
+	p := a.Provider
+	p.Once.Do(func() { p.init() })
+
+	fqdn, value := ChallengeRecord(domain, keyAuth)
+
+	// GET /dns/getroot/{hostname}
+	root, err := p.Client.GetRootDomain(ctx, fqdn)
+	if err != nil {
+		return fmt.Errorf("find Dynu domain of %s: %w", fqdn, err)
+	}
+
+	ttl := a.TTL
+	if ttl <= 0 {
+		ttl = defaultChallengeTTL
+	}
+	requested := libdns.Record{Type: "TXT", Name: root.Node, Value: value, TTL: ttl}
+
+	// the TTL policy and validation of AppendRecords, in the Dynu domain of
+	// the challenge
+	prepared, err := p.prepareRecordsIn(root.DomainName, root.DomainName, []libdns.Record{requested}, nil)
+	if err != nil {
+		return fmt.Errorf("%s TXT record: %w", fqdn, err)
+	}
+	requested = prepared[0]
+	dnsRecord, err := libdnsRecordToDnsRecord(requested, root.DomainName, root.DomainName)
+	if err != nil {
+		return fmt.Errorf("%s TXT record: %w", fqdn, err)
+	}
+
+	// POST /dns/{id}/record
+	created, err := p.Client.AddOrUpdateRecord(ctx, root.ID, dnsRecord, true)
+
+	var after *libdns.Record
+	if err == nil {
+		record := dnsRecordToLibdnsRecord(*created, root.DomainName)
+		after = &record
+	}
+	auditErr := p.audit(ctx, "append", root.DomainName, root.ID, nil, requested, after, err)
+	if err != nil {
+		err = fmt.Errorf("create %s TXT record: %w", fqdn, err)
+		if auditErr != nil {
+			err = errors.Join(err, fmt.Errorf("audit: %w", auditErr))
+		}
+		return err
+	}
+	if auditErr != nil {
+		return errors.Join(fmt.Errorf("audit: %w", auditErr), a.delete(ctx, root.ID, root.DomainName, after))
+	}
+
+	if !a.SkipPropagation {
+		if err := a.propagation().Wait(ctx, root.DomainName, requested); err != nil {
+			return errors.Join(err, a.delete(ctx, root.ID, root.DomainName, after))
+		}
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.records == nil {
+		a.records = make(map[challengeKey]challengeRecord)
+	}
+	a.records[challengeKey{fqdn, value}] = challengeRecord{domainID: root.ID, zone: root.DomainName, record: *after}
+
+	return nil
+}
+
+// CleanUpContext is CleanUp with a context.
+func (a *ACMEChallenge) CleanUpContext(ctx context.Context, domain, _, keyAuth string) error {
+	p := a.Provider
+	p.Once.Do(func() { p.init() })
+
+	fqdn, value := ChallengeRecord(domain, keyAuth)
+	key := challengeKey{fqdn, value}
+
+	a.mutex.Lock()
+	created, ok := a.records[key]
+	a.mutex.Unlock()
+	if !ok {
+		// Present failed, lego cleans up nevertheless
+		return nil
+	}
+
+	if err := a.delete(ctx, created.domainID, created.zone, &created.record); err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	delete(a.records, key)
+	a.mutex.Unlock()
+	return nil
+}
+
+func (a *ACMEChallenge) propagation() *PropagationChecker {
+	if a.Propagation == nil {
+		return &PropagationChecker{}
+	}
+	return a.Propagation
+}
+
+// delete removes a created record by its ID, leaving other TXT records of
+// the same name alone
+func (a *ACMEChallenge) delete(ctx context.Context, domainID int64, zone string, record *libdns.Record) error {
+	if record == nil {
+		return nil
+	}
+
+	// DELETE /dns/{id}/record/{dnsRecordId}
+	err := a.Provider.Client.DeleteRecord(ctx, domainID, record.ID)
+	if auditErr := a.Provider.audit(ctx, "delete", zone, domainID, record, *record, nil, err); auditErr != nil {
+		return errors.Join(err, fmt.Errorf("audit: %w", auditErr))
+	}
+	if err != nil {
+		return fmt.Errorf("delete challenge record %s: %w", record.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package dynu
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	challengeValue      = "61rBZ_4knHblO0MNoxFsXZ_eTFUHum0B6IVRbhvUn5I"
+	otherChallengeValue = "5EalHcjIOU0zc2kgLmbyRe00mtT3AIkNWDqWOgoA51g"
+)
+
+func TestChallengeRecord(t *testing.T) {
+	fqdn, value := ChallengeRecord("www.example.com.", "token.thumbprint")
+	assert.Equal(t, "_acme-challenge.www.example.com", fqdn)
+	assert.Equal(t, challengeValue, value)
+
+	fqdn, _ = ChallengeRecord("*.example.com", "token.thumbprint")
+	assert.Equal(t, "_acme-challenge.example.com", fqdn)
+}
+
+func TestACMEChallengePresentCleanUp(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.",
+		DNSRecord{ID: 1, Type: "TXT", NodeName: "_acme-challenge.www", TextData: "unrelated", TTL: 300, State: true},
+	)
+	acme := &ACMEChallenge{Provider: provider, SkipPropagation: true}
+
+	require.NoError(t, acme.Present("www.example.com", "token", "token.thumbprint"))
+	require.NoError(t, acme.Present("www.example.com", "other", "other.thumbprint"))
+
+	records := fake.Records()
+	require.Len(t, records, 3)
+	assert.Equal(t, DNSRecord{
+		ID: 1000, Type: "TXT", DomainID: 100, DomainName: "example.com", NodeName: "_acme-challenge.www",
+		Hostname: "_acme-challenge.www.example.com", TextData: challengeValue, TTL: 60, State: true,
+	}, records[1])
+	assert.Equal(t, otherChallengeValue, records[2].TextData)
+
+	require.NoError(t, acme.CleanUp("www.example.com", "token", "token.thumbprint"))
+
+	records = fake.Records()
+	require.Len(t, records, 2)
+	assert.Equal(t, "unrelated", records[0].TextData)
+	assert.Equal(t, otherChallengeValue, records[1].TextData)
+
+	// nothing left to clean up
+	assert.NoError(t, acme.CleanUp("www.example.com", "token", "token.thumbprint"))
+	assert.Contains(t, fake.Requests(), "GET /dns/getroot/_acme-challenge.www.example.com")
+}
+
+func TestACMEChallengeTTL(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.")
+	acme := &ACMEChallenge{Provider: provider, TTL: 2 * time.Minute, SkipPropagation: true}
+
+	require.NoError(t, acme.Present("example.com", "token", "token.thumbprint"))
+	assert.Equal(t, "_acme-challenge", fake.Records()[0].NodeName)
+	assert.Equal(t, 120, fake.Records()[0].TTL)
+}
+
+func TestACMEChallengeTTLPolicy(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.")
+	provider.TTLPolicy = TTLPolicy{Min: 5 * time.Minute}
+	acme := &ACMEChallenge{Provider: provider, SkipPropagation: true}
+
+	require.NoError(t, acme.Present("example.com", "token", "token.thumbprint"))
+	assert.Equal(t, 300, fake.Records()[0].TTL)
+
+	provider.TTLPolicy.Mode = TTLReject
+	err := acme.Present("example.com", "other", "other.thumbprint")
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.ErrorContains(t, err, "_acme-challenge.example.com TXT record")
+	assert.Len(t, fake.Records(), 1)
+	assert.NotContains(t, fake.Requests()[len(fake.Requests())-1], "POST")
+}
+
+func TestACMEChallengeCleanUpAfterFailedPresent(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.")
+	acme := &ACMEChallenge{Provider: provider, SkipPropagation: true}
+
+	require.Error(t, acme.Present("example.org", "token", "token.thumbprint"))
+	assert.NoError(t, acme.CleanUp("example.org", "token", "token.thumbprint"))
+	assert.NotContains(t, fake.Requests(), "DELETE /dns/100/record/1000")
+}
+
+func TestACMEChallengePropagation(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.")
+	checker, ns1, ns2 := newPropagationTest(t)
+	acme := &ACMEChallenge{Provider: provider, Propagation: checker}
+
+	ns1.set(t, `_acme-challenge.example.com. 60 IN TXT "`+challengeValue+`"`)
+	ns2.set(t, `_acme-challenge.example.com. 60 IN TXT "`+challengeValue+`"`)
+	require.NoError(t, acme.PresentContext(context.Background(), "example.com", "token", "token.thumbprint"))
+	assert.Len(t, fake.Records(), 1)
+
+	timeout, interval := acme.Timeout()
+	assert.Equal(t, 2*time.Second, timeout)
+	assert.Equal(t, 10*time.Millisecond, interval)
+}
+
+func TestACMEChallengePropagationTimeout(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.")
+	checker, _, _ := newPropagationTest(t)
+	checker.Timeout = 50 * time.Millisecond
+	acme := &ACMEChallenge{Provider: provider, Propagation: checker}
+
+	err := acme.Present("example.com", "token", "token.thumbprint")
+	assert.ErrorContains(t, err, "not visible")
+
+	// the record is removed again, as lego does not clean up after a failed Present
+	assert.Empty(t, fake.Records())
+	assert.Contains(t, fake.Requests(), "DELETE /dns/100/record/1000")
+}
+
+func TestACMEChallengeUnknownDomain(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.")
+	acme := &ACMEChallenge{Provider: provider, SkipPropagation: true}
+
+	err := acme.Present("example.org", "token", "token.thumbprint")
+	assert.ErrorContains(t, err, "find Dynu domain of _acme-challenge.example.org")
+	assert.Empty(t, fake.Records())
+}
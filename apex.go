@@ -0,0 +1,199 @@
+package dynu
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// IDs of the apex A and AAAA records that stand for the IPv4 and IPv6
+// addresses of the Dynu domain itself, see Provider.ApexAddresses.
+const (
+	DomainIPv4RecordID = "domain-ipv4"
+	DomainIPv6RecordID = "domain-ipv6"
+)
+
+// isDomainAddressID reports whether a record ID is one of the domain-level
+// addresses
+func isDomainAddressID(id string) bool {
+	return id == DomainIPv4RecordID || id == DomainIPv6RecordID
+}
+
+// domainAddressID returns the record ID of the domain-level address of a
+// record type, "" if the type has none
+func domainAddressID(recordType string) string {
+	switch recordType {
+	case "A":
+		return DomainIPv4RecordID
+	case "AAAA":
+		return DomainIPv6RecordID
+	default:
+		return ""
+	}
+}
+
+// isApexAddress reports whether a record is written to the domain-level
+// addresses rather than as a record: an apex A or AAAA record without ID
+// or with the ID of the domain-level address
+func (p *Provider) isApexAddress(record libdns.Record, dnsRecord DNSRecord) bool {
+	if !p.ApexAddresses || domainAddressID(record.Type) == "" {
+		return false
+	}
+	return isDomainAddressID(record.ID) || record.ID == "" && dnsRecord.NodeName == ""
+}
+
+// domainRecord converts a record of a domain: a Dynu record or, with ID 0,
+// a domain-level address
+func domainRecord(dnsRecord DNSRecord, domain string) libdns.Record {
+	record := dnsRecordToLibdnsRecord(dnsRecord, domain)
+	if dnsRecord.ID == 0 {
+		record.ID = domainAddressID(dnsRecord.Type)
+	}
+	return record
+}
+
+// domainAddressRecords returns the enabled addresses of a domain as records
+// at its apex
+func domainAddressRecords(domain Domain) []DNSRecord {
+	var dnsRecords []DNSRecord
+	apex := DNSRecord{
+		DomainID:   domain.ID,
+		DomainName: domain.Name,
+		Hostname:   domain.Name,
+		TTL:        domain.TTL,
+		State:      true,
+	}
+	if domain.Ipv4 && domain.Ipv4Address != "" {
+		dnsRecord := apex
+		dnsRecord.Type = "A"
+		dnsRecord.Ipv4Address = domain.Ipv4Address
+		dnsRecords = append(dnsRecords, dnsRecord)
+	}
+	if domain.Ipv6 && domain.Ipv6Address != "" {
+		dnsRecord := apex
+		dnsRecord.Type = "AAAA"
+		dnsRecord.Ipv6Address = domain.Ipv6Address
+		dnsRecords = append(dnsRecords, dnsRecord)
+	}
+	return dnsRecords
+}
+
+// readApexRecords returns the domain-level addresses of the domain as apex
+// records; nothing is fetched unless ApexAddresses is set
+func (p *Provider) readApexRecords(ctx context.Context, domainID int64, domain string) ([]libdns.Record, error) {
+	if !p.ApexAddresses {
+		return nil, nil
+	}
+
+	// GET /dns/{id}
+	dnsDomain, err := p.Client.GetDomain(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []libdns.Record
+	for _, dnsRecord := range domainAddressRecords(*dnsDomain) {
+		records = append(records, p.readApexRecord(dnsRecord, domain))
+	}
+	return records, nil
+}
+
+func (p *Provider) readApexRecord(dnsRecord DNSRecord, domain string) libdns.Record {
+	record := p.readRecord(dnsRecord, domain)
+	record.ID = domainAddressID(record.Type)
+	return record
+}
+
+// writeApexAddress sets the domain-level address of the record type. With
+// create, it refuses to replace another address, as Dynu keeps only one of
+// each type. The record TTL only becomes the TTL of the domain, which both
+// addresses share, with setTTL, i.e. if the caller asked for a TTL rather
+// than getting the default of the TTL policy.
+func (p *Provider) writeApexAddress(ctx context.Context, domainID int64, domain string, record libdns.Record, create, setTTL bool) (*libdns.Record, error) {
+	id := domainAddressID(record.Type)
+	if record.ID != "" && record.ID != id {
+		return nil, fmt.Errorf("record ID %s is not the domain-level address of %s records", record.ID, record.Type)
+	}
+
+	// GET /dns/{id}
+	dnsDomain, err := p.Client.GetDomain(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *dnsDomain
+	address, enabled := &updated.Ipv4Address, &updated.Ipv4
+	if record.Type == "AAAA" {
+		address, enabled = &updated.Ipv6Address, &updated.Ipv6
+	}
+	if create && *enabled && *address != "" && *address != record.Value {
+		return nil, fmt.Errorf("domain %s already has the %s address %s, which only SetRecords replaces", dnsDomain.Name, record.Type, *address)
+	}
+	*address, *enabled = record.Value, true
+	if setTTL && record.TTL > 0 {
+		updated.TTL = int(record.TTL / time.Second)
+	}
+
+	if updated.Request() != dnsDomain.Request() {
+		// POST /dns/{id}
+		if err := p.Client.UpdateDomain(ctx, domainID, updated.Request()); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, dnsRecord := range domainAddressRecords(updated) {
+		if dnsRecord.Type == record.Type {
+			after := p.readApexRecord(dnsRecord, domain)
+			return &after, nil
+		}
+	}
+	return nil, fmt.Errorf("domain %s has no %s address", dnsDomain.Name, record.Type)
+}
+
+// deleteApexAddress disables the domain-level address with the record ID
+func (p *Provider) deleteApexAddress(ctx context.Context, domainID int64, id string) error {
+	// GET /dns/{id}
+	dnsDomain, err := p.Client.GetDomain(ctx, domainID)
+	if err != nil {
+		return err
+	}
+
+	request := dnsDomain.Request()
+	if id == DomainIPv4RecordID {
+		request.Ipv4, request.Ipv4Address = false, ""
+	} else {
+		request.Ipv6, request.Ipv6Address = false, ""
+	}
+	if request == dnsDomain.Request() {
+		return nil
+	}
+
+	// POST /dns/{id}
+	return p.Client.UpdateDomain(ctx, domainID, request)
+}
+
+// restoreApexAddress sets the domain-level address with the record ID and the
+// TTL of the domain back to their values in original
+func (p *Provider) restoreApexAddress(ctx context.Context, domainID int64, id string, original Domain) error {
+	// GET /dns/{id}
+	dnsDomain, err := p.Client.GetDomain(ctx, domainID)
+	if err != nil {
+		return err
+	}
+
+	request := dnsDomain.Request()
+	if id == DomainIPv4RecordID {
+		request.Ipv4, request.Ipv4Address = original.Ipv4, original.Ipv4Address
+	} else {
+		request.Ipv6, request.Ipv6Address = original.Ipv6, original.Ipv6Address
+	}
+	request.TTL = original.TTL
+	if request == dnsDomain.Request() {
+		return nil
+	}
+
+	// POST /dns/{id}
+	return p.Client.UpdateDomain(ctx, domainID, request)
+}
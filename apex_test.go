@@ -0,0 +1,276 @@
+package dynu
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/stretchr/testify/assert"
+)
+
+// newApexProvider returns a provider with ApexAddresses whose domain has the
+// IPv4 address 203.0.113.1
+func newApexProvider(t *testing.T, records ...DNSRecord) (*Provider, *fakeDynu) {
+	provider, fake := newFakeProvider(t, "example.com.", records...)
+	provider.ApexAddresses = true
+	fake.domains[0].Ipv4Address = "203.0.113.1"
+	return provider, fake
+}
+
+func TestGetRecordsApexAddresses(t *testing.T) {
+	provider, fake := newApexProvider(t, DNSRecord{Type: "A", NodeName: "www", Ipv4Address: "203.0.113.2", TTL: 300})
+
+	records, err := provider.GetRecords(context.TODO(), "example.com.")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []libdns.Record{
+			{ID: "1000", Type: "A", Name: "www", Value: "203.0.113.2", TTL: 300 * time.Second},
+			{ID: DomainIPv4RecordID, Type: "A", Name: "@", Value: "203.0.113.1", TTL: 90 * time.Second},
+		}, records)
+	}
+
+	// the domain is only fetched with ApexAddresses
+	provider.ApexAddresses = false
+	requests := len(fake.Requests())
+	records, err = provider.GetRecords(context.TODO(), "example.com.")
+	if assert.NoError(t, err) {
+		assert.Len(t, records, 1)
+	}
+	assert.NotContains(t, fake.Requests()[requests:], "GET /dns/100")
+}
+
+func TestSetApexAddress(t *testing.T) {
+	provider, fake := newApexProvider(t)
+
+	records, err := provider.SetRecords(context.TODO(), "example.com.", []libdns.Record{
+		{Type: "A", Name: "@", Value: "203.0.113.9", TTL: 120 * time.Second},
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []libdns.Record{
+			{ID: DomainIPv4RecordID, Type: "A", Name: "@", Value: "203.0.113.9", TTL: 120 * time.Second},
+		}, records)
+	}
+
+	domain := fake.Domains()[0]
+	assert.Equal(t, "203.0.113.9", domain.Ipv4Address)
+	assert.Equal(t, 120, domain.TTL)
+	assert.True(t, domain.Ipv6)
+	assert.Empty(t, fake.Records())
+	assert.Contains(t, fake.Requests(), "POST /dns/100")
+}
+
+func TestAppendApexAddress(t *testing.T) {
+	provider, fake := newApexProvider(t)
+
+	records, err := provider.AppendRecords(context.TODO(), "example.com.", []libdns.Record{
+		{Type: "AAAA", Name: "@", Value: "2001:db8::1"},
+		{Type: "A", Name: "www", Value: "203.0.113.2"},
+	})
+	if assert.NoError(t, err) && assert.Len(t, records, 2) {
+		assert.Equal(t, DomainIPv6RecordID, records[0].ID)
+		assert.Equal(t, "1000", records[1].ID)
+	}
+	assert.Equal(t, "2001:db8::1", fake.Domains()[0].Ipv6Address)
+	assert.Len(t, fake.Records(), 1)
+
+	// without a TTL of its own, the address keeps the TTL of the domain
+	// rather than the default of the TTL policy
+	assert.Equal(t, 90, fake.Domains()[0].TTL)
+	assert.Equal(t, 300, fake.Records()[0].TTL)
+
+	// Dynu keeps one address of each type
+	_, err = provider.AppendRecords(context.TODO(), "example.com.", []libdns.Record{
+		{Type: "A", Name: "@", Value: "203.0.113.9"},
+	})
+	assert.ErrorContains(t, err, "domain example.com already has the A address 203.0.113.1")
+	assert.Equal(t, "203.0.113.1", fake.Domains()[0].Ipv4Address)
+}
+
+func TestAppendApexAddressIgnoresID(t *testing.T) {
+	provider, fake := newApexProvider(t)
+
+	// appending ignores IDs, so an apex address with one is still written to
+	// the domain rather than as a record
+	records, err := provider.AppendRecords(context.TODO(), "example.com.", []libdns.Record{
+		{ID: "1000", Type: "AAAA", Name: "@", Value: "2001:db8::1"},
+	})
+	if assert.NoError(t, err) && assert.Len(t, records, 1) {
+		assert.Equal(t, DomainIPv6RecordID, records[0].ID)
+	}
+	assert.Equal(t, "2001:db8::1", fake.Domains()[0].Ipv6Address)
+	assert.Empty(t, fake.Records())
+}
+
+func TestApexRecordWithRecordID(t *testing.T) {
+	provider, fake := newApexProvider(t, DNSRecord{Type: "A", Ipv4Address: "203.0.113.5", TTL: 300})
+
+	// an apex record kept as a Dynu record stays one
+	_, err := provider.SetRecords(context.TODO(), "example.com.", []libdns.Record{
+		{ID: "1000", Type: "A", Name: "@", Value: "203.0.113.6"},
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "203.0.113.6", fake.Records()[0].Ipv4Address)
+		assert.Equal(t, "203.0.113.1", fake.Domains()[0].Ipv4Address)
+	}
+
+	_, err = provider.SetRecords(context.TODO(), "example.com.", []libdns.Record{
+		{ID: DomainIPv4RecordID, Type: "AAAA", Name: "@", Value: "2001:db8::1"},
+	})
+	assert.ErrorContains(t, err, "record ID domain-ipv4 is not the domain-level address of AAAA records")
+}
+
+func TestDeleteApexAddress(t *testing.T) {
+	provider, fake := newApexProvider(t)
+
+	deleted, err := provider.DeleteRecords(context.TODO(), "example.com.", []libdns.Record{
+		{ID: DomainIPv4RecordID, Type: "A", Name: "@", Value: "203.0.113.1"},
+	})
+	if assert.NoError(t, err) {
+		assert.Len(t, deleted, 1)
+	}
+	assert.False(t, fake.Domains()[0].Ipv4)
+	assert.NotContains(t, fake.Requests(), "DELETE /dns/100/record/"+DomainIPv4RecordID)
+
+	records, err := provider.GetRecords(context.TODO(), "example.com.")
+	if assert.NoError(t, err) {
+		assert.Empty(t, records)
+	}
+}
+
+func TestQueryApexAddresses(t *testing.T) {
+	provider, _ := newApexProvider(t)
+
+	records, err := provider.QueryRecords(context.TODO(), "example.com.", RecordQuery{Name: "@", Types: []string{"A"}})
+	if assert.NoError(t, err) && assert.Len(t, records, 1) {
+		assert.Equal(t, DomainIPv4RecordID, records[0].ID)
+	}
+
+	records, err = provider.QueryRecords(context.TODO(), "example.com.", RecordQuery{Name: "www"})
+	if assert.NoError(t, err) {
+		assert.Empty(t, records)
+	}
+}
+
+func TestSyncApexAddresses(t *testing.T) {
+	provider, fake := newApexProvider(t)
+
+	spec := &ZoneSpec{Zone: "example.com.", TTL: 300, Records: []SpecRecord{
+		{Name: "@", Type: "A", Value: "203.0.113.9"},
+		{Name: "@", Type: "AAAA", Value: "2001:db8::1"},
+	}}
+	plan, err := provider.PlanSync(context.TODO(), spec)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if assert.Len(t, plan.Changes, 2) {
+		assert.Equal(t, SyncUpdate, plan.Changes[0].Action)
+		assert.Equal(t, DomainIPv4RecordID, plan.Changes[0].After.ID)
+		assert.Equal(t, SyncCreate, plan.Changes[1].Action)
+	}
+
+	_, err = provider.ApplySync(context.TODO(), plan)
+	if assert.NoError(t, err) {
+		domain := fake.Domains()[0]
+		assert.Equal(t, "203.0.113.9", domain.Ipv4Address)
+		assert.Equal(t, "2001:db8::1", domain.Ipv6Address)
+		assert.Empty(t, fake.Records())
+	}
+}
+
+func TestApplyBatchApexAddresses(t *testing.T) {
+	provider, fake := newApexProvider(t)
+
+	report, err := provider.ApplyBatch(context.TODO(), "example.com.", Batch{
+		Create: []libdns.Record{{Type: "AAAA", Name: "@", Value: "2001:db8::1", TTL: 120 * time.Second}},
+		Update: []libdns.Record{{ID: DomainIPv4RecordID, Type: "A", Name: "@", Value: "203.0.113.9", TTL: 120 * time.Second}},
+	})
+	if assert.NoError(t, err) && assert.Len(t, report.Operations, 2) {
+		assert.Equal(t, "203.0.113.1", report.Operations[0].Before.Value)
+		assert.Equal(t, DomainIPv6RecordID, report.Operations[1].After.ID)
+	}
+	domain := fake.Domains()[0]
+	assert.Equal(t, "203.0.113.9", domain.Ipv4Address)
+	assert.Equal(t, "2001:db8::1", domain.Ipv6Address)
+	assert.Equal(t, 120, domain.TTL)
+	assert.Empty(t, fake.Records())
+
+	_, err = provider.ApplyBatch(context.TODO(), "example.com.", Batch{
+		Delete: []libdns.Record{{ID: DomainIPv6RecordID}},
+	})
+	assert.NoError(t, err)
+	assert.False(t, fake.Domains()[0].Ipv6)
+
+	requests := len(fake.Requests())
+	_, err = provider.ApplyBatch(context.TODO(), "example.com.", Batch{
+		Update: []libdns.Record{{ID: DomainIPv4RecordID, Type: "AAAA", Name: "@", Value: "2001:db8::2"}},
+	})
+	assert.ErrorContains(t, err, `update record "domain-ipv4": not the domain-level address of AAAA records`)
+	assert.NotContains(t, fake.Requests()[requests:], "POST /dns/100")
+}
+
+func TestApplyBatchApexRollback(t *testing.T) {
+	provider, fake := newApexProvider(t)
+	fake.fail("POST", "/dns/100/record", 1)
+
+	report, err := provider.ApplyBatch(context.TODO(), "example.com.", Batch{
+		Create: []libdns.Record{
+			{Type: "AAAA", Name: "@", Value: "2001:db8::1"},
+			{Type: "TXT", Name: "new", Value: "added"},
+		},
+		Update: []libdns.Record{{ID: DomainIPv4RecordID, Type: "A", Name: "@", Value: "203.0.113.9", TTL: 120 * time.Second}},
+	})
+	assert.Error(t, err)
+	if assert.Len(t, report.Operations, 3) {
+		assert.Equal(t, BatchRolledBack, report.Operations[0].Status)
+		assert.Equal(t, BatchRolledBack, report.Operations[1].Status)
+		assert.Equal(t, BatchFailed, report.Operations[2].Status)
+	}
+
+	domain := fake.Domains()[0]
+	assert.Equal(t, "203.0.113.1", domain.Ipv4Address)
+	assert.Empty(t, domain.Ipv6Address)
+	assert.Equal(t, 90, domain.TTL)
+	assert.Empty(t, fake.Records())
+}
+
+func TestRestoreApexAddresses(t *testing.T) {
+	provider, fake := newApexProvider(t)
+	ctx := context.TODO()
+
+	snapshot, err := provider.Snapshot(ctx, "example.com.")
+	if !assert.NoError(t, err) {
+		return
+	}
+	var buffer bytes.Buffer
+	_, err = snapshot.WriteTo(&buffer)
+	if !assert.NoError(t, err) {
+		return
+	}
+	snapshot, err = ReadSnapshot(&buffer)
+	if !assert.NoError(t, err) || !assert.NotNil(t, snapshot.Domain) {
+		return
+	}
+	assert.Equal(t, "203.0.113.1", snapshot.Domain.Ipv4Address)
+
+	_, err = provider.SetRecords(ctx, "example.com.", []libdns.Record{{Type: "A", Name: "@", Value: "203.0.113.9", TTL: 120 * time.Second}})
+	assert.NoError(t, err)
+	_, err = provider.AppendRecords(ctx, "example.com.", []libdns.Record{{Type: "AAAA", Name: "@", Value: "2001:db8::1"}})
+	assert.NoError(t, err)
+
+	changes, err := provider.Restore(ctx, "example.com.", snapshot)
+	if assert.NoError(t, err) && assert.Len(t, changes, 2) {
+		assert.Equal(t, SyncUpdate, changes[0].Action)
+		assert.Equal(t, SyncDelete, changes[1].Action)
+	}
+	domain := fake.Domains()[0]
+	assert.Equal(t, "203.0.113.1", domain.Ipv4Address)
+	assert.Empty(t, domain.Ipv6Address)
+	assert.Equal(t, 90, domain.TTL)
+	assert.Empty(t, fake.Records())
+
+	// nothing left to do
+	changes, err = provider.Restore(ctx, "example.com.", snapshot)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
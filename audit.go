@@ -0,0 +1,143 @@
+package dynu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// AuditEvent describes a DNS change attempted through a Provider.
+type AuditEvent struct {
+	Time time.Time `json:"time"`
+	// Actor is the value set with WithActor, if any.
+	Actor string `json:"actor,omitempty"`
+	// Operation is "append", "set" or "delete".
+	Operation string `json:"operation"`
+	Zone      string `json:"zone"`
+	DomainID  int64  `json:"domainId"`
+	// Before is the record as it was in Dynu; nil for new records.
+	Before *libdns.Record `json:"before,omitempty"`
+	// Requested is the record passed to the Provider.
+	Requested libdns.Record `json:"requested"`
+	// After is the record as returned by Dynu; nil for deletions and failures.
+	After   *libdns.Record `json:"after,omitempty"`
+	Success bool           `json:"success"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// AuditSink receives an AuditEvent for every change made by a Provider. An
+// error returned by the sink is reported by the Provider method making the
+// change.
+type AuditSink interface {
+	Record(ctx context.Context, event AuditEvent) error
+}
+
+type actorKey struct{}
+
+// WithActor returns a context carrying the actor recorded in audit events,
+// e.g. the user or service on whose behalf the change is made.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set with WithActor.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// FileAuditSink appends audit events as JSON lines to a file.
+type FileAuditSink struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+// NewFileAuditSink opens path for appending, creating it if needed.
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuditSink{file: file}, nil
+}
+
+// Record writes the event and syncs the file so it survives a crash.
+func (s *FileAuditSink) Record(_ context.Context, event AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// Close closes the underlying file.
+func (s *FileAuditSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.file.Close()
+}
+
+// audit sends an event to the AuditSink, if any
+func (p *Provider) audit(ctx context.Context, operation, zone string, domainID int64, before *libdns.Record, requested libdns.Record, after *libdns.Record, err error) error {
+	if p.AuditSink == nil {
+		return nil
+	}
+
+	event := AuditEvent{
+		Time:      time.Now().UTC(),
+		Actor:     ActorFromContext(ctx),
+		Operation: operation,
+		Zone:      zone,
+		DomainID:  domainID,
+		Before:    before,
+		Requested: requested,
+		After:     after,
+		Success:   err == nil,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	return p.AuditSink.Record(ctx, event)
+}
+
+// currentRecords returns the records of the domain by id for the before state
+// of audit events; nothing is fetched without an AuditSink
+func (p *Provider) currentRecords(ctx context.Context, domainID int64, domain string) (map[string]libdns.Record, error) {
+	if p.AuditSink == nil {
+		return nil, nil
+	}
+
+	dnsRecords, err := p.Client.GetRecords(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make(map[string]libdns.Record, len(dnsRecords))
+	for _, dnsRecord := range dnsRecords {
+		records[fmt.Sprint(dnsRecord.ID)] = dnsRecordToLibdnsRecord(dnsRecord, domain)
+	}
+
+	// GET /dns/{id}, only with ApexAddresses
+	apexRecords, err := p.readApexRecords(ctx, domainID, domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range apexRecords {
+		records[record.ID] = record
+	}
+	return records, nil
+}
@@ -0,0 +1,100 @@
+package dynu
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditSetAndDelete(t *testing.T) {
+	provider, _ := newFakeProvider(t, "example.com.", DNSRecord{ID: 1, Type: "TXT", NodeName: "test", TextData: "old", TTL: 120})
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := NewFileAuditSink(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer sink.Close()
+	provider.AuditSink = sink
+
+	ctx := WithActor(context.TODO(), "alice")
+	record := libdns.Record{ID: "1", Type: "TXT", Name: "test", Value: "new", TTL: 120 * time.Second}
+
+	updated, err := provider.SetRecords(ctx, "example.com.", []libdns.Record{record})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = provider.DeleteRecords(ctx, "example.com.", updated)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	file, err := os.Open(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer file.Close()
+
+	var events []AuditEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event AuditEvent
+		if assert.NoError(t, json.Unmarshal(scanner.Bytes(), &event)) {
+			events = append(events, event)
+		}
+	}
+
+	if !assert.Len(t, events, 2) {
+		return
+	}
+
+	assert.Equal(t, "set", events[0].Operation)
+	assert.Equal(t, "alice", events[0].Actor)
+	assert.Equal(t, "example.com.", events[0].Zone)
+	assert.Equal(t, int64(100), events[0].DomainID)
+	assert.True(t, events[0].Success)
+	assert.Equal(t, "old", events[0].Before.Value)
+	assert.Equal(t, "new", events[0].After.Value)
+	assert.False(t, events[0].Time.IsZero())
+
+	assert.Equal(t, "delete", events[1].Operation)
+	assert.Equal(t, "new", events[1].Before.Value)
+	assert.Nil(t, events[1].After)
+}
+
+type memoryAuditSink struct {
+	events []AuditEvent
+}
+
+func (s *memoryAuditSink) Record(_ context.Context, event AuditEvent) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func TestAuditFailure(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.")
+	fake.fail("POST", "/dns/100/record", 1)
+
+	sink := &memoryAuditSink{}
+	provider.AuditSink = sink
+
+	record := libdns.Record{Type: "TXT", Name: "test", Value: "value", TTL: 120 * time.Second}
+	_, err := provider.AppendRecords(context.TODO(), "example.com.", []libdns.Record{record})
+	assert.Error(t, err)
+
+	if assert.Len(t, sink.events, 1) {
+		assert.Equal(t, "append", sink.events[0].Operation)
+		assert.False(t, sink.events[0].Success)
+		assert.Contains(t, sink.events[0].Error, "injected failure")
+		assert.Nil(t, sink.events[0].Before)
+		assert.Nil(t, sink.events[0].After)
+		assert.Equal(t, record, sink.events[0].Requested)
+	}
+}
@@ -0,0 +1,289 @@
+package dynu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/libdns/libdns"
+)
+
+// Batch is a set of changes to apply to a zone as a whole.
+type Batch struct {
+	Create []libdns.Record
+	// Update and Delete refer to existing records by ID.
+	Update []libdns.Record
+	Delete []libdns.Record
+}
+
+// BatchStatus is the state of a BatchOperation after Provider.ApplyBatch.
+type BatchStatus string
+
+const (
+	// BatchApplied is a change that was made and kept.
+	BatchApplied BatchStatus = "applied"
+	// BatchFailed is the change whose failure aborted the batch.
+	BatchFailed BatchStatus = "failed"
+	// BatchNotApplied is a change skipped after the batch was aborted.
+	BatchNotApplied BatchStatus = "not_applied"
+	// BatchRolledBack is a change that was made and then undone.
+	BatchRolledBack BatchStatus = "rolled_back"
+	// BatchRollbackFailed is a change that was made but could not be undone.
+	BatchRollbackFailed BatchStatus = "rollback_failed"
+)
+
+// BatchOperation reports on a single change of a Batch.
+type BatchOperation struct {
+	Action SyncAction
+	// Record is the requested record.
+	Record libdns.Record
+	// Before is the record before the change; nil for creations.
+	Before *libdns.Record
+	// After is the record as returned by Dynu; nil for deletions.
+	After  *libdns.Record
+	Status BatchStatus
+	Err    error
+	// RestoredID is the new ID of a deleted record re-created by the rollback.
+	RestoredID string
+}
+
+// BatchReport lists every operation of a batch in the order of application.
+type BatchReport struct {
+	Operations []BatchOperation
+	// RolledBack is set if a failure aborted the batch.
+	RolledBack bool
+}
+
+// ApplyBatch applies the changes of the batch: updates first, then
+// deletions, then creations. On the first failure, the changes already made
+// are undone from a snapshot taken before, in reverse order. Re-created
+// records get new IDs. The report tells which changes were applied, rolled
+// back or could not be restored.
+func (p *Provider) ApplyBatch(ctx context.Context, zone string, batch Batch) (*BatchReport, error) {
+	p.Once.Do(func() { p.init() })
+
+	domain := zoneToFqdn(zone)
+
+	// GET /dns/getroot/{hostname}
+	dnsHostName, err := p.Client.GetRootDomain(ctx, p.ownDomainOf(zone))
+	if err != nil {
+		return nil, err
+	}
+
+	// GET /dns/{id}/record
+	dnsRecords, err := p.Client.GetRecords(ctx, dnsHostName.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := make(map[string]DNSRecord, len(dnsRecords))
+	for _, dnsRecord := range dnsRecords {
+		snapshot[strconv.FormatInt(dnsRecord.ID, 10)] = dnsRecord
+	}
+
+	// the domain-level addresses are part of the snapshot, with ID 0
+	var dnsDomain *Domain
+	var apexRecords []DNSRecord
+	if p.ApexAddresses {
+		// GET /dns/{id}
+		if dnsDomain, err = p.Client.GetDomain(ctx, dnsHostName.ID); err != nil {
+			return nil, err
+		}
+		apexRecords = domainAddressRecords(*dnsDomain)
+		for _, dnsRecord := range apexRecords {
+			snapshot[domainAddressID(dnsRecord.Type)] = dnsRecord
+		}
+	}
+
+	report := &BatchReport{}
+	writes := make([]libdns.Record, 0, len(batch.Update)+len(batch.Delete)+len(batch.Create))
+	requests := make([]DNSRecord, 0, len(batch.Update)+len(batch.Delete)+len(batch.Create))
+
+	// check everything before the first change
+	deleted := make(map[string]bool, len(batch.Delete))
+	for _, record := range batch.Delete {
+		deleted[record.ID] = true
+	}
+	var existing []libdns.Record
+	for _, dnsRecord := range append(append([]DNSRecord{}, dnsRecords...), apexRecords...) {
+		if record := domainRecord(dnsRecord, domain); !deleted[record.ID] {
+			existing = append(existing, record)
+		}
+	}
+
+	var errs []error
+	changed := append(append([]libdns.Record{}, batch.Update...), batch.Create...)
+	prepared, prepareErr := p.prepareRecords(zone, changed, existing)
+	if prepareErr != nil {
+		errs = append(errs, prepareErr)
+		prepared = changed
+	}
+
+	// write is the record with the TTL policy applied
+	add := func(action SyncAction, record, write libdns.Record) {
+		operation := BatchOperation{Action: action, Record: record, Status: BatchNotApplied}
+		var request DNSRecord
+
+		if action != SyncCreate {
+			current, ok := snapshot[record.ID]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s record %q: no record with this ID", action, record.ID))
+			}
+			before := domainRecord(current, domain)
+			operation.Before = &before
+		}
+		if action != SyncDelete && prepareErr == nil {
+			var err error
+			request, err = libdnsRecordToDnsRecord(write, domain, p.ownDomainOf(domain))
+			if err != nil {
+				errs = append(errs, err)
+			}
+			if p.ApexAddresses && isDomainAddressID(record.ID) && record.ID != domainAddressID(write.Type) {
+				errs = append(errs, fmt.Errorf("%s record %q: not the domain-level address of %s records", action, record.ID, write.Type))
+			}
+		}
+
+		report.Operations = append(report.Operations, operation)
+		writes = append(writes, write)
+		requests = append(requests, request)
+	}
+	for i, record := range batch.Update {
+		add(SyncUpdate, record, prepared[i])
+	}
+	for _, record := range batch.Delete {
+		add(SyncDelete, record, record)
+	}
+	for i, record := range batch.Create {
+		add(SyncCreate, record, prepared[len(batch.Update)+i])
+	}
+	if len(errs) > 0 {
+		return report, errors.Join(errs...)
+	}
+
+	for i := range report.Operations {
+		operation := &report.Operations[i]
+
+		err := p.applyBatchOperation(ctx, zone, dnsHostName.ID, domain, operation, writes[i], requests[i])
+		if err == nil {
+			operation.Status = BatchApplied
+			continue
+		}
+
+		operation.Status = BatchFailed
+		operation.Err = err
+		report.RolledBack = true
+
+		rollbackErrs := []error{fmt.Errorf("batch aborted: %s record %+v: %w", operation.Action, operation.Record, err)}
+		for j := i - 1; j >= 0; j-- {
+			if err := p.rollbackBatchOperation(ctx, zone, dnsHostName.ID, domain, &report.Operations[j], snapshot, dnsDomain); err != nil {
+				rollbackErrs = append(rollbackErrs, err)
+			}
+		}
+		return report, errors.Join(rollbackErrs...)
+	}
+
+	return report, nil
+}
+
+// applyBatchOperation makes a change; write is the record with the TTL
+// policy applied and request the same record for Dynu
+func (p *Provider) applyBatchOperation(ctx context.Context, zone string, domainID int64, domain string, operation *BatchOperation, write libdns.Record, request DNSRecord) error {
+	var err error
+
+	switch {
+	case operation.Action == SyncDelete && p.ApexAddresses && isDomainAddressID(operation.Record.ID):
+		// GET and POST /dns/{id}
+		err = p.deleteApexAddress(ctx, domainID, operation.Record.ID)
+	case operation.Action == SyncDelete:
+		// DELETE /dns/{id}/record/{dnsRecordId}
+		err = p.Client.DeleteRecord(ctx, domainID, operation.Record.ID)
+	case p.isApexAddress(write, request):
+		// GET and POST /dns/{id}
+		operation.After, err = p.writeApexAddress(ctx, domainID, domain, write, operation.Action == SyncCreate, operation.Record.TTL != 0)
+	default:
+		// POST /dns/{id}/record[/{dnsRecordId}]
+		var updateResponse *DNSRecord
+		updateResponse, err = p.Client.AddOrUpdateRecord(ctx, domainID, request, operation.Action == SyncCreate)
+		if err == nil {
+			after := dnsRecordToLibdnsRecord(*updateResponse, domain)
+			operation.After = &after
+		}
+	}
+
+	if auditErr := p.audit(ctx, operation.Action.auditOperation(), zone, domainID, operation.Before, operation.Record, operation.After, err); auditErr != nil && err == nil {
+		err = fmt.Errorf("audit: %w", auditErr)
+	}
+	return err
+}
+
+// rollbackBatchOperation undoes an applied operation using the snapshot; the
+// domain-level addresses are restored from dnsDomain
+func (p *Provider) rollbackBatchOperation(ctx context.Context, zone string, domainID int64, domain string, operation *BatchOperation, snapshot map[string]DNSRecord, dnsDomain *Domain) error {
+	var err error
+	var restored *libdns.Record
+	auditOperation := "delete"
+
+	switch operation.Action {
+	case SyncCreate:
+		if p.ApexAddresses && isDomainAddressID(operation.After.ID) {
+			// GET and POST /dns/{id}
+			err = p.restoreApexAddress(ctx, domainID, operation.After.ID, *dnsDomain)
+			if original, ok := snapshot[operation.After.ID]; ok && err == nil {
+				record := domainRecord(original, domain)
+				restored = &record
+				auditOperation = "set"
+			}
+			break
+		}
+
+		// DELETE /dns/{id}/record/{dnsRecordId}
+		err = p.Client.DeleteRecord(ctx, domainID, operation.After.ID)
+	case SyncUpdate, SyncDelete:
+		original := snapshot[operation.Before.ID]
+		original.StatusCode = 0
+		auditOperation = "set"
+		if operation.Action == SyncDelete {
+			auditOperation = "append"
+		}
+
+		if p.ApexAddresses && isDomainAddressID(operation.Before.ID) {
+			// GET and POST /dns/{id}
+			if err = p.restoreApexAddress(ctx, domainID, operation.Before.ID, *dnsDomain); err == nil {
+				restored = operation.Before
+				if operation.Action == SyncDelete {
+					operation.RestoredID = operation.Before.ID
+				}
+			}
+			break
+		}
+
+		// POST /dns/{id}/record[/{dnsRecordId}]
+		var updateResponse *DNSRecord
+		updateResponse, err = p.Client.AddOrUpdateRecord(ctx, domainID, original, operation.Action == SyncDelete)
+		if err == nil {
+			record := dnsRecordToLibdnsRecord(*updateResponse, domain)
+			restored = &record
+			if operation.Action == SyncDelete {
+				operation.RestoredID = record.ID
+			}
+		}
+	}
+
+	requested := operation.Record
+	if operation.Before != nil {
+		requested = *operation.Before
+	}
+	if auditErr := p.audit(ctx, auditOperation, zone, domainID, operation.After, requested, restored, err); auditErr != nil && err == nil {
+		err = fmt.Errorf("audit: %w", auditErr)
+	}
+
+	if err != nil {
+		operation.Status = BatchRollbackFailed
+		operation.Err = err
+		return fmt.Errorf("rollback of %s record %+v: %w", operation.Action, operation.Record, err)
+	}
+
+	operation.Status = BatchRolledBack
+	return nil
+}
@@ -0,0 +1,117 @@
+package dynu
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyBatch(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.",
+		DNSRecord{ID: 1, Type: "A", NodeName: "www", Ipv4Address: "198.51.100.1", TTL: 300, State: true},
+		DNSRecord{ID: 2, Type: "TXT", NodeName: "old", TextData: "remove me", TTL: 300, State: true},
+	)
+
+	report, err := provider.ApplyBatch(context.TODO(), "example.com.", Batch{
+		Create: []libdns.Record{{Type: "TXT", Name: "new", Value: "added", TTL: 300 * time.Second}},
+		Update: []libdns.Record{{ID: "1", Type: "A", Name: "www", Value: "203.0.113.1", TTL: 300 * time.Second}},
+		Delete: []libdns.Record{{ID: "2"}},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.False(t, report.RolledBack)
+	for _, operation := range report.Operations {
+		assert.Equal(t, BatchApplied, operation.Status)
+	}
+
+	records := fake.Records()
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, "203.0.113.1", records[0].Ipv4Address)
+		assert.Equal(t, "added", records[1].TextData)
+	}
+}
+
+func TestApplyBatchRollback(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.",
+		DNSRecord{ID: 1, Type: "A", NodeName: "www", Ipv4Address: "198.51.100.1", TTL: 300, State: true},
+		DNSRecord{ID: 2, Type: "TXT", NodeName: "old", TextData: "remove me", TTL: 300, State: true},
+	)
+	fake.fail("POST", "/dns/100/record", 1)
+
+	report, err := provider.ApplyBatch(context.TODO(), "example.com.", Batch{
+		Create: []libdns.Record{{Type: "TXT", Name: "new", Value: "added", TTL: 300 * time.Second}},
+		Update: []libdns.Record{{ID: "1", Type: "A", Name: "www", Value: "203.0.113.1", TTL: 300 * time.Second}},
+		Delete: []libdns.Record{{ID: "2"}},
+	})
+	assert.Error(t, err)
+	assert.True(t, report.RolledBack)
+
+	if assert.Len(t, report.Operations, 3) {
+		assert.Equal(t, BatchRolledBack, report.Operations[0].Status)
+		assert.Equal(t, BatchRolledBack, report.Operations[1].Status)
+		assert.NotEmpty(t, report.Operations[1].RestoredID)
+		assert.Equal(t, BatchFailed, report.Operations[2].Status)
+	}
+
+	records := fake.Records()
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, "198.51.100.1", records[0].Ipv4Address)
+		assert.Equal(t, "remove me", records[1].TextData)
+		assert.Equal(t, "old", records[1].NodeName)
+	}
+}
+
+func TestApplyBatchRollbackFailure(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.",
+		DNSRecord{ID: 1, Type: "A", NodeName: "www", Ipv4Address: "198.51.100.1", TTL: 300, State: true},
+	)
+	fake.fail("POST", "/dns/100/record", 1)
+
+	// let the update through, but fail restoring it
+	updates := 0
+	provider.Client.Middleware = []Middleware{func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			if call.Endpoint == "AddOrUpdateRecord" && strings.HasSuffix(call.URL, "/record/1") {
+				updates++
+				if updates > 1 {
+					return errors.New("connection reset")
+				}
+			}
+			return next(ctx, call)
+		}
+	}}
+
+	report, err := provider.ApplyBatch(context.TODO(), "example.com.", Batch{
+		Create: []libdns.Record{{Type: "TXT", Name: "new", Value: "added", TTL: 300 * time.Second}},
+		Update: []libdns.Record{{ID: "1", Type: "A", Name: "www", Value: "203.0.113.1", TTL: 300 * time.Second}},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "connection reset")
+	}
+
+	if assert.Len(t, report.Operations, 2) {
+		assert.Equal(t, BatchRollbackFailed, report.Operations[0].Status)
+		assert.Error(t, report.Operations[0].Err)
+		assert.Equal(t, BatchFailed, report.Operations[1].Status)
+	}
+	assert.Equal(t, "203.0.113.1", fake.Records()[0].Ipv4Address)
+}
+
+func TestApplyBatchUnknownID(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.")
+
+	report, err := provider.ApplyBatch(context.TODO(), "example.com.", Batch{
+		Create: []libdns.Record{{Type: "TXT", Name: "new", Value: "added", TTL: 300 * time.Second}},
+		Delete: []libdns.Record{{ID: "42"}},
+	})
+	assert.Error(t, err)
+	assert.False(t, report.RolledBack)
+	assert.Empty(t, fake.Records(), "nothing must be changed")
+}
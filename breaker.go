@@ -0,0 +1,186 @@
+package dynu
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails all requests without calling the API.
+	CircuitOpen
+	// CircuitHalfOpen lets a single probe request through to test recovery.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// CircuitOpenError is returned for requests rejected by an open CircuitBreaker.
+type CircuitOpenError struct {
+	// RetryAfter is when the breaker will let a probe request through.
+	RetryAfter time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open: Dynu API unavailable, retry after %s", e.RetryAfter.Format(time.RFC3339))
+}
+
+// CircuitBreaker stops calling the Dynu API during sustained failures. Network
+// errors, status 429 and 5xx statuses count as failures; other API errors do
+// not. The zero value is ready to use with the defaults below.
+type CircuitBreaker struct {
+	// FailureRate of requests within Window that opens the circuit; 0.5 by default.
+	FailureRate float64
+	// MinRequests within Window before FailureRate is considered; 10 by default.
+	MinRequests int
+	// Window over which requests are counted; one minute by default.
+	Window time.Duration
+	// OpenTimeout is how long the circuit stays open before half-opening;
+	// 30 seconds by default.
+	OpenTimeout time.Duration
+
+	mutex       sync.Mutex
+	state       CircuitState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probing     bool
+}
+
+// State returns the current state, e.g. for health checks.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == CircuitOpen && !time.Now().Before(b.openedAt.Add(b.openTimeout())) {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+func (b *CircuitBreaker) failureRate() float64 {
+	if b.FailureRate <= 0 {
+		return 0.5
+	}
+	return b.FailureRate
+}
+
+func (b *CircuitBreaker) minRequests() int {
+	if b.MinRequests <= 0 {
+		return 10
+	}
+	return b.MinRequests
+}
+
+func (b *CircuitBreaker) window() time.Duration {
+	if b.Window <= 0 {
+		return time.Minute
+	}
+	return b.Window
+}
+
+func (b *CircuitBreaker) openTimeout() time.Duration {
+	if b.OpenTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return b.OpenTimeout
+}
+
+// allow returns an error if a request must not be made; a nil breaker allows everything
+func (b *CircuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := time.Now()
+
+	switch b.state {
+	case CircuitOpen:
+		retryAfter := b.openedAt.Add(b.openTimeout())
+		if now.Before(retryAfter) {
+			return &CircuitOpenError{RetryAfter: retryAfter}
+		}
+		b.state = CircuitHalfOpen
+		b.probing = true
+		return nil
+	case CircuitHalfOpen:
+		if b.probing {
+			return &CircuitOpenError{RetryAfter: now.Add(b.openTimeout())}
+		}
+		b.probing = true
+		return nil
+	default:
+		if now.Sub(b.windowStart) >= b.window() {
+			b.windowStart = now
+			b.requests = 0
+			b.failures = 0
+		}
+		return nil
+	}
+}
+
+// done records the result of an allowed request; aborted requests, e.g. a
+// cancelled context, count neither way
+func (b *CircuitBreaker) done(failed, aborted bool) {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == CircuitHalfOpen {
+		b.probing = false
+		switch {
+		case aborted:
+		case failed:
+			b.open()
+		default:
+			b.state = CircuitClosed
+			b.windowStart = time.Now()
+			b.requests = 0
+			b.failures = 0
+		}
+		return
+	}
+
+	if b.state != CircuitClosed || aborted {
+		return
+	}
+
+	b.requests++
+	if failed {
+		b.failures++
+	}
+
+	if b.requests >= b.minRequests() && float64(b.failures)/float64(b.requests) >= b.failureRate() {
+		b.open()
+	}
+}
+
+func (b *CircuitBreaker) open() {
+	b.state = CircuitOpen
+	b.openedAt = time.Now()
+	b.requests = 0
+	b.failures = 0
+}
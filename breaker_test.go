@@ -0,0 +1,71 @@
+package dynu
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	var calls atomic.Int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"statusCode":503,"type":"Unavailable","message":"down"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"statusCode":200,"dnsRecords":[]}`))
+	}))
+
+	breaker := &CircuitBreaker{FailureRate: 0.5, MinRequests: 2, OpenTimeout: 50 * time.Millisecond}
+	client.CircuitBreaker = breaker
+	ctx := context.TODO()
+
+	for i := 0; i < 2; i++ {
+		_, err := client.GetRecords(ctx, 1)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, CircuitOpen, breaker.State())
+
+	_, err := client.GetRecords(ctx, 1)
+	var openErr *CircuitOpenError
+	assert.ErrorAs(t, err, &openErr)
+	assert.Equal(t, int32(2), calls.Load(), "open circuit must not call the API")
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, CircuitHalfOpen, breaker.State())
+
+	// failed probe opens the circuit again
+	_, err = client.GetRecords(ctx, 1)
+	assert.Error(t, err)
+	assert.Equal(t, CircuitOpen, breaker.State())
+
+	time.Sleep(60 * time.Millisecond)
+	healthy.Store(true)
+
+	_, err = client.GetRecords(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, CircuitClosed, breaker.State())
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"statusCode":404,"type":"Not Found","message":"no such domain"}`))
+	}))
+
+	breaker := &CircuitBreaker{MinRequests: 1}
+	client.CircuitBreaker = breaker
+
+	for i := 0; i < 3; i++ {
+		_, err := client.GetRecords(context.TODO(), 1)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, CircuitClosed, breaker.State())
+}
@@ -0,0 +1,203 @@
+// Package caddy registers the Dynu DNS provider as the Caddy module
+// dns.providers.dynu, e.g. for ACME DNS-01 challenges:
+//
+//	tls {
+//		dns dynu {env.DYNU_API_TOKEN}
+//	}
+//
+// Import it for its side effect in a Caddy build, e.g. with
+// xcaddy build --with github.com/taviowong/libdns-dynu/caddy.
+package caddy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/libdns/libdns"
+	dynu "github.com/taviowong/libdns-dynu"
+)
+
+func init() {
+	caddy.RegisterModule(Provider{})
+}
+
+// Provider wraps the Dynu provider as a Caddy module.
+type Provider struct {
+	// APIToken is the Dynu API token.
+	APIToken string `json:"api_token,omitempty"`
+	// OwnDomain is the Dynu domain owning the records, if the zone is a
+	// subdomain of it; the zone itself by default.
+	OwnDomain string `json:"own_domain,omitempty"`
+	// BaseURL overrides the Dynu API endpoint.
+	BaseURL string `json:"base_url,omitempty"`
+	// Retries is how many times failed API requests are retried; requests
+	// creating records only if they never reached Dynu.
+	Retries int `json:"retries,omitempty"`
+	// Timeout of each API request; 30 seconds by default.
+	Timeout caddy.Duration `json:"timeout,omitempty"`
+
+	provider *dynu.Provider
+}
+
+// CaddyModule returns the Caddy module information.
+func (Provider) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "dns.providers.dynu",
+		New: func() caddy.Module { return new(Provider) },
+	}
+}
+
+// Provision replaces placeholders such as {env.DYNU_API_TOKEN} and sets up
+// the Dynu client.
+func (p *Provider) Provision(ctx caddy.Context) error {
+	repl := caddy.NewReplacer()
+	p.APIToken = repl.ReplaceAll(p.APIToken, "")
+	p.OwnDomain = repl.ReplaceAll(p.OwnDomain, "")
+	p.BaseURL = repl.ReplaceAll(p.BaseURL, "")
+
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	client := dynu.NewClient(p.APIToken)
+	client.MaxRetries = p.Retries
+	if p.Timeout > 0 {
+		client.HTTPClient.Timeout = time.Duration(p.Timeout)
+	}
+	if p.BaseURL != "" {
+		if err := client.SetBaseURL(p.BaseURL); err != nil {
+			return fmt.Errorf("dynu: %w", err)
+		}
+	}
+
+	p.provider = &dynu.Provider{APIToken: p.APIToken, OwnDomain: p.OwnDomain, Client: client}
+	return nil
+}
+
+// Validate reports all configuration errors at once.
+func (p *Provider) Validate() error {
+	var errs []error
+	if p.APIToken == "" {
+		errs = append(errs, errors.New("dynu: api_token is required (is the environment variable set?)"))
+	}
+	if p.BaseURL != "" {
+		if u, err := url.Parse(p.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("dynu: base_url %q must be an absolute URL such as https://api.dynu.com/v2", p.BaseURL))
+		}
+	}
+	if p.Retries < 0 {
+		errs = append(errs, fmt.Errorf("dynu: retries must not be negative, got %d", p.Retries))
+	}
+	if p.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("dynu: timeout must not be negative, got %s", time.Duration(p.Timeout)))
+	}
+	return errors.Join(errs...)
+}
+
+// UnmarshalCaddyfile sets up the provider from Caddyfile tokens. Syntax:
+//
+//	dynu [<api_token>] {
+//		api_token  <api_token>
+//		own_domain <domain>
+//		base_url   <url>
+//		retries    <count>
+//		timeout    <duration>
+//	}
+func (p *Provider) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	for d.Next() {
+		if d.NextArg() {
+			p.APIToken = d.Val()
+		}
+		if d.NextArg() {
+			return d.ArgErr()
+		}
+
+		for nesting := d.Nesting(); d.NextBlock(nesting); {
+			option := d.Val()
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			value := d.Val()
+			if d.NextArg() {
+				return d.ArgErr()
+			}
+
+			switch option {
+			case "api_token":
+				if p.APIToken != "" {
+					return d.Err("api_token already set")
+				}
+				p.APIToken = value
+			case "own_domain":
+				if p.OwnDomain != "" {
+					return d.Err("own_domain already set")
+				}
+				p.OwnDomain = value
+			case "base_url":
+				if p.BaseURL != "" {
+					return d.Err("base_url already set")
+				}
+				p.BaseURL = value
+			case "retries":
+				retries, err := strconv.Atoi(value)
+				if err != nil || retries < 0 {
+					return d.Errf("retries must be a non-negative integer, got %q", value)
+				}
+				p.Retries = retries
+			case "timeout":
+				timeout, err := caddy.ParseDuration(value)
+				if err != nil || timeout <= 0 {
+					return d.Errf("timeout must be a positive duration such as 30s, got %q", value)
+				}
+				p.Timeout = caddy.Duration(timeout)
+			default:
+				return d.Errf("unrecognized subdirective %q", option)
+			}
+		}
+	}
+
+	if p.APIToken == "" {
+		return d.Err("missing api_token")
+	}
+	return nil
+}
+
+// GetRecords lists all the records in the zone.
+func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
+	return p.provider.GetRecords(ctx, zone)
+}
+
+// AppendRecords adds records to the zone. It returns the records that were added.
+func (p *Provider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	return p.provider.AppendRecords(ctx, zone, records)
+}
+
+// SetRecords sets the records in the zone, either by updating existing records or creating new ones.
+// It returns the updated records.
+func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	return p.provider.SetRecords(ctx, zone, records)
+}
+
+// DeleteRecords deletes the records from the zone. It returns the records that were deleted.
+func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	return p.provider.DeleteRecords(ctx, zone, records)
+}
+
+// Interface guards
+var (
+	_ caddy.Module          = (*Provider)(nil)
+	_ caddy.Provisioner     = (*Provider)(nil)
+	_ caddy.Validator       = (*Provider)(nil)
+	_ caddyfile.Unmarshaler = (*Provider)(nil)
+
+	_ libdns.RecordGetter   = (*Provider)(nil)
+	_ libdns.RecordAppender = (*Provider)(nil)
+	_ libdns.RecordSetter   = (*Provider)(nil)
+	_ libdns.RecordDeleter  = (*Provider)(nil)
+)
@@ -0,0 +1,151 @@
+package caddy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalCaddyfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Provider
+		err      string
+	}{
+		{
+			name:     "token argument",
+			input:    `dynu {env.DYNU_API_TOKEN}`,
+			expected: Provider{APIToken: "{env.DYNU_API_TOKEN}"},
+		},
+		{
+			name: "all options",
+			input: `dynu {
+				api_token secret
+				own_domain example.com
+				base_url http://localhost:8080/v2
+				retries 3
+				timeout 10s
+			}`,
+			expected: Provider{
+				APIToken:  "secret",
+				OwnDomain: "example.com",
+				BaseURL:   "http://localhost:8080/v2",
+				Retries:   3,
+				Timeout:   caddy.Duration(10 * time.Second),
+			},
+		},
+		{name: "missing token", input: `dynu`, err: "missing api_token"},
+		{name: "extra argument", input: `dynu secret other`, err: "wrong argument count"},
+		{name: "token twice", input: "dynu secret {\n api_token other\n}", err: "api_token already set"},
+		{name: "option without value", input: "dynu {\n api_token\n}", err: "wrong argument count"},
+		{name: "negative retries", input: "dynu secret {\n retries -1\n}", err: `retries must be a non-negative integer, got "-1"`},
+		{name: "invalid timeout", input: "dynu secret {\n timeout soon\n}", err: `timeout must be a positive duration such as 30s, got "soon"`},
+		{name: "unknown option", input: "dynu secret {\n ttl 60\n}", err: `unrecognized subdirective "ttl"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var provider Provider
+			err := provider.UnmarshalCaddyfile(caddyfile.NewTestDispenser(test.input))
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, provider)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	provider := Provider{BaseURL: "api.dynu.com", Retries: -1, Timeout: -1}
+	err := provider.Validate()
+	assert.EqualError(t, err, "dynu: api_token is required (is the environment variable set?)\n"+
+		`dynu: base_url "api.dynu.com" must be an absolute URL such as https://api.dynu.com/v2`+"\n"+
+		"dynu: retries must not be negative, got -1\n"+
+		"dynu: timeout must not be negative, got -1ns")
+
+	assert.NoError(t, (&Provider{APIToken: "secret"}).Validate())
+}
+
+func newCaddyContext(t *testing.T) caddy.Context {
+	ctx, cancel := caddy.NewContext(caddy.Context{Context: context.Background()})
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestProvision(t *testing.T) {
+	var apiKeys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKeys = append(apiKeys, r.Header.Get("API-Key"))
+		switch r.URL.Path {
+		case "/v2/dns/getroot/example.com":
+			_ = json.NewEncoder(w).Encode(map[string]any{"statusCode": 200, "id": 100, "domainName": "example.com"})
+		case "/v2/dns/100/record":
+			_ = json.NewEncoder(w).Encode(map[string]any{"statusCode": 200, "dnsRecords": []map[string]any{
+				{"id": 1, "recordType": "TXT", "hostname": "_acme-challenge.example.com", "textData": "token", "ttl": 60},
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("DYNU_TEST_TOKEN", "secret")
+	t.Setenv("DYNU_TEST_URL", server.URL+"/v2")
+
+	provider := Provider{APIToken: "{env.DYNU_TEST_TOKEN}", OwnDomain: "example.com", BaseURL: "{env.DYNU_TEST_URL}"}
+	require.NoError(t, provider.Provision(newCaddyContext(t)))
+	assert.Equal(t, "secret", provider.APIToken)
+
+	records, err := provider.GetRecords(context.Background(), "example.com.")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "_acme-challenge", records[0].Name)
+	assert.Equal(t, []string{"secret", "secret"}, apiKeys)
+}
+
+func TestProvisionWithoutOwnDomain(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/v2/dns/getroot/my.dynu.com":
+			_ = json.NewEncoder(w).Encode(map[string]any{"statusCode": 200, "id": 100, "domainName": "my.dynu.com"})
+		case "/v2/dns/100/record":
+			_ = json.NewEncoder(w).Encode(map[string]any{"statusCode": 200, "dnsRecords": []map[string]any{}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	// the zone is the Dynu domain
+	provider := Provider{APIToken: "secret", BaseURL: server.URL + "/v2"}
+	require.NoError(t, provider.Provision(newCaddyContext(t)))
+
+	_, err := provider.GetRecords(context.Background(), "my.dynu.com.")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/v2/dns/getroot/my.dynu.com", "/v2/dns/100/record"}, paths)
+}
+
+func TestProvisionUnsetEnv(t *testing.T) {
+	provider := Provider{APIToken: "{env.DYNU_TEST_UNSET}"}
+	err := provider.Provision(newCaddyContext(t))
+	assert.EqualError(t, err, "dynu: api_token is required (is the environment variable set?)")
+}
+
+func TestModuleRegistered(t *testing.T) {
+	info, err := caddy.GetModule("dns.providers.dynu")
+	require.NoError(t, err)
+	assert.IsType(t, new(Provider), info.New())
+}
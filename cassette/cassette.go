@@ -0,0 +1,332 @@
+// Package cassette records Dynu API interactions to fixture files and
+// replays them, so tests written against the real API can run offline.
+//
+// Use a Recorder as the transport of Client.HTTPClient while running against
+// the real API, then Save the cassette. A Replayer loaded from that file
+// serves the responses back in the same order. Interactions written by hand
+// instead are marked Synthetic, so they are not taken for recordings.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Cassette is the content of a fixture file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request with its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+	// Synthetic marks an interaction written by hand rather than recorded
+	// from the real API; the Recorder never sets it.
+	Synthetic bool `json:"synthetic,omitempty"`
+}
+
+// Request is a recorded request. Headers are not recorded so the API key
+// never reaches the fixture.
+type Request struct {
+	Method string `json:"method"`
+	// Path includes the query string, but not the scheme and host.
+	Path string          `json:"path"`
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int             `json:"statusCode"`
+	Body       json.RawMessage `json:"body,omitempty"`
+}
+
+// JSON fields holding Dynu account identifiers, replaced by stable fake values
+var idFields = map[string]bool{
+	"id":       true,
+	"domainId": true,
+	"groupId":  true,
+}
+
+// JSON fields holding secrets, e.g. the token of a domain, whose values are
+// replaced by redactedSecret wherever they appear
+var secretFields = map[string]bool{
+	"token":        true,
+	"password":     true,
+	"apiKey":       true,
+	"access_token": true,
+}
+
+const redactedSecret = "redacted"
+
+// first fake id handed out by a Recorder
+const firstFakeID = 1000
+
+// Recorder is an http.RoundTripper that forwards requests to Transport and
+// records the redacted interactions.
+type Recorder struct {
+	// Transport makes the real requests; http.DefaultTransport if nil.
+	Transport http.RoundTripper
+	// Redactions replaces sensitive strings, e.g. your domain name, in
+	// recorded paths and bodies.
+	Redactions map[string]string
+
+	mutex        sync.Mutex
+	interactions []Interaction
+	ids          map[string]string
+	secrets      map[string]bool
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// the request only contains ids learned from earlier responses, so it is
+	// redacted first to keep the mapping in the order ids were seen
+	interaction := Interaction{
+		Request: Request{
+			Method: req.Method,
+			Path:   r.redactPath(req.URL.RequestURI()),
+			Body:   r.redactBody(reqBody),
+		},
+		Response: Response{StatusCode: resp.StatusCode},
+	}
+	interaction.Response.Body = r.redactBody(respBody)
+	r.interactions = append(r.interactions, interaction)
+
+	return resp, nil
+}
+
+// Cassette returns the interactions recorded so far.
+func (r *Recorder) Cassette() Cassette {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return Cassette{Interactions: append([]Interaction(nil), r.interactions...)}
+}
+
+// Save writes the recorded interactions to path.
+func (r *Recorder) Save(path string) error {
+	data, err := json.MarshalIndent(r.Cassette(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func (r *Recorder) redactString(s string) string {
+	for secret, replacement := range r.Redactions {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, replacement)
+		}
+	}
+	for secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redactedSecret)
+	}
+	return s
+}
+
+func (r *Recorder) fakeID(id string) string {
+	if r.ids == nil {
+		r.ids = make(map[string]string)
+	}
+	fake, ok := r.ids[id]
+	if !ok {
+		fake = strconv.Itoa(firstFakeID + len(r.ids))
+		r.ids[id] = fake
+	}
+	return fake
+}
+
+func (r *Recorder) addSecret(secret string) {
+	if r.secrets == nil {
+		r.secrets = make(map[string]bool)
+	}
+	r.secrets[secret] = true
+}
+
+func (r *Recorder) redactPath(path string) string {
+	path = r.redactString(path)
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if fake, ok := r.ids[segment]; ok {
+			segments[i] = fake
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func (r *Recorder) redactBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		// not JSON; keep it as a JSON string
+		raw, _ := json.Marshal(r.redactString(string(body)))
+		return raw
+	}
+
+	raw, _ := json.Marshal(r.redactValue(value))
+	return raw
+}
+
+func (r *Recorder) redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		// secrets first, so other fields of the object are redacted too
+		for key, field := range v {
+			if secret, ok := field.(string); ok && secretFields[key] && secret != "" {
+				r.addSecret(secret)
+			}
+		}
+		for key, field := range v {
+			if number, ok := field.(json.Number); ok && idFields[key] && number != "0" {
+				v[key] = json.Number(r.fakeID(number.String()))
+			} else {
+				v[key] = r.redactValue(field)
+			}
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = r.redactValue(v[i])
+		}
+		return v
+	case string:
+		return r.redactString(v)
+	default:
+		return v
+	}
+}
+
+// Replayer is an http.RoundTripper serving the interactions of a Cassette in
+// order. A request that does not match the next interaction fails.
+type Replayer struct {
+	mutex        sync.Mutex
+	interactions []Interaction
+}
+
+// NewReplayer creates a Replayer for the cassette.
+func NewReplayer(cassette Cassette) *Replayer {
+	return &Replayer{interactions: cassette.Interactions}
+}
+
+// Load reads a cassette written by Recorder.Save.
+func Load(path string) (*Replayer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("cassette %s: %w", path, err)
+	}
+
+	return NewReplayer(cassette), nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.interactions) == 0 {
+		return nil, fmt.Errorf("cassette: no interaction left for %s %s", req.Method, req.URL.RequestURI())
+	}
+
+	next := r.interactions[0]
+	if next.Request.Method != req.Method || next.Request.Path != req.URL.RequestURI() || !jsonEqual(next.Request.Body, reqBody) {
+		return nil, fmt.Errorf("cassette: unexpected request %s %s %s, want %s %s %s",
+			req.Method, req.URL.RequestURI(), reqBody, next.Request.Method, next.Request.Path, next.Request.Body)
+	}
+	r.interactions = r.interactions[1:]
+
+	return &http.Response{
+		StatusCode:    next.Response.StatusCode,
+		Status:        fmt.Sprintf("%d %s", next.Response.StatusCode, http.StatusText(next.Response.StatusCode)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(next.Response.Body)),
+		ContentLength: int64(len(next.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Done returns an error if some interactions were not replayed.
+func (r *Replayer) Done() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.interactions) > 0 {
+		return errors.New("cassette: " + strconv.Itoa(len(r.interactions)) + " interactions not replayed")
+	}
+	return nil
+}
+
+func jsonEqual(a, b []byte) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+
+	var va, vb any
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return bytes.Equal(a, b)
+	}
+	return reflect.DeepEqual(va, vb)
+}
@@ -0,0 +1,103 @@
+package cassette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/dns/getroot/secret.dynu.net":
+			_, _ = w.Write([]byte(`{"statusCode":200,"id":4242,"domainName":"secret.dynu.net"}`))
+		case "/v2/dns/4242/record":
+			_, _ = w.Write([]byte(`{"statusCode":200,"dnsRecords":[{"id":77,"domainId":4242,"hostname":"www.secret.dynu.net"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	recorder := &Recorder{Redactions: map[string]string{"secret.dynu.net": "example.com"}}
+	client := &http.Client{Transport: recorder}
+
+	for _, path := range []string{"/v2/dns/getroot/secret.dynu.net", "/v2/dns/4242/record"} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header.Set("API-Key", "secret-key")
+		resp, err := client.Do(req)
+		if !assert.NoError(t, err) {
+			return
+		}
+		_ = resp.Body.Close()
+	}
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if !assert.NoError(t, recorder.Save(path)) {
+		return
+	}
+
+	replayer, err := Load(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	client = &http.Client{Transport: replayer}
+
+	resp, err := client.Get("https://api.dynu.com/v2/dns/getroot/example.com")
+	if !assert.NoError(t, err) {
+		return
+	}
+	body, _ := io.ReadAll(resp.Body)
+	assert.JSONEq(t, `{"statusCode":200,"id":1000,"domainName":"example.com"}`, string(body))
+
+	resp, err = client.Get("https://api.dynu.com/v2/dns/1000/record")
+	if !assert.NoError(t, err) {
+		return
+	}
+	body, _ = io.ReadAll(resp.Body)
+	assert.JSONEq(t, `{"statusCode":200,"dnsRecords":[{"id":1001,"domainId":1000,"hostname":"www.example.com"}]}`, string(body))
+	assert.False(t, strings.Contains(string(body), "secret"))
+
+	assert.NoError(t, replayer.Done())
+}
+
+func TestRecordRedactsSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"statusCode":200,"id":4242,"name":"example.com","token":"s3cr3t-t0ken","group":"s3cr3t-t0ken group"}`))
+	}))
+	defer server.Close()
+
+	recorder := &Recorder{}
+	client := &http.Client{Transport: recorder}
+
+	resp, err := client.Get(server.URL + "/v2/dns/4242")
+	if !assert.NoError(t, err) {
+		return
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Contains(t, string(body), "s3cr3t-t0ken", "the caller gets the real response")
+
+	interactions := recorder.Cassette().Interactions
+	if assert.Len(t, interactions, 1) {
+		assert.JSONEq(t, `{"statusCode":200,"id":1000,"name":"example.com","token":"redacted","group":"redacted group"}`,
+			string(interactions[0].Response.Body))
+	}
+}
+
+func TestReplayUnexpectedRequest(t *testing.T) {
+	replayer := NewReplayer(Cassette{Interactions: []Interaction{{
+		Request:  Request{Method: http.MethodGet, Path: "/v2/dns"},
+		Response: Response{StatusCode: 200, Body: []byte(`{"statusCode":200}`)},
+	}}})
+	client := &http.Client{Transport: replayer}
+
+	_, err := client.Get("https://api.dynu.com/v2/dns/1")
+	assert.Error(t, err)
+	assert.Error(t, replayer.Done())
+}
@@ -7,19 +7,38 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"reflect"
 	"sync"
 	"time"
 )
 
 const defaultBaseURL = "https://api.dynu.com/v2"
 
+// delay before the first retry; doubled for every further attempt
+const retryBaseDelay = 500 * time.Millisecond
+
 type Client struct {
 	baseURL    *url.URL
 	HTTPClient *http.Client
 	APIToken   string
 
+	// Metrics receives API usage observations; nil disables them.
+	Metrics Metrics
+	// MaxRetries is how many times a request failing with a network error,
+	// status 429 or a 5xx status is retried. Requests creating a record or
+	// domain are only retried if they failed before reaching Dynu, as Dynu
+	// may have acted on them otherwise.
+	MaxRetries int
+	// Middleware is wrapped around every request attempt, the first entry
+	// being the outermost.
+	Middleware []Middleware
+	// CircuitBreaker, if set, fails requests fast during sustained API failures.
+	CircuitBreaker *CircuitBreaker
+
+	// one request is sent to the API at a time
 	mutex sync.Mutex
 }
 
@@ -33,18 +52,39 @@ func NewClient(APIToken string) *Client {
 	}
 }
 
+// SetBaseURL points the client to another API endpoint than
+// https://api.dynu.com/v2, e.g. a local fake for tests.
+func (c *Client) SetBaseURL(rawURL string) error {
+	baseURL, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid base URL: %w", err)
+	}
+	if baseURL.Scheme == "" || baseURL.Host == "" {
+		return fmt.Errorf("invalid base URL %q: scheme and host required", rawURL)
+	}
+
+	c.baseURL = baseURL
+	return nil
+}
+
 func (c *Client) joinUrlPath(elem ...string) *url.URL {
 	return c.baseURL.JoinPath(elem...)
 }
 
+func (c *Client) metrics() Metrics {
+	if c.Metrics == nil {
+		return nopMetrics{}
+	}
+	return c.Metrics
+}
+
 func (c *Client) GetRootDomain(ctx context.Context, hostname string) (*DNSHostname, error) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	hostname = asciiName(hostname)
 
 	endpoint := c.joinUrlPath("dns", "getroot", hostname)
 	apiResponse := DNSHostname{}
 	apiException := APIException{}
-	err := c.doWithCustomError(ctx, http.MethodGet, endpoint.String(), nil, &apiResponse, &apiException)
+	err := c.doWithCustomError(ctx, "GetRootDomain", http.MethodGet, endpoint.String(), nil, &apiResponse, &apiException)
 	if err != nil {
 		return nil, err
 	}
@@ -58,14 +98,53 @@ func (c *Client) GetRootDomain(ctx context.Context, hostname string) (*DNSHostna
 }
 
 func (c *Client) GetRecords(ctx context.Context, hostnameId int64) ([]DNSRecord, error) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	endpoint := c.joinUrlPath("dns", fmt.Sprint(hostnameId), "record")
 
+	apiResponse := RecordsResponse{}
+	apiException := APIException{}
+	err := c.doWithCustomError(ctx, "GetRecords", http.MethodGet, endpoint.String(), nil, &apiResponse, &apiException)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiResponse.StatusCode != 200 {
+		return nil, fmt.Errorf("API error: %w", apiException)
+	}
+
+	return apiResponse.DNSRecords, nil
+}
+
+// GetRawRecords returns the records of a domain as the JSON returned by Dynu.
+func (c *Client) GetRawRecords(ctx context.Context, hostnameId int64) ([]json.RawMessage, error) {
 	endpoint := c.joinUrlPath("dns", fmt.Sprint(hostnameId), "record")
 
+	apiResponse := RawRecordsResponse{}
+	apiException := APIException{}
+	err := c.doWithCustomError(ctx, "GetRawRecords", http.MethodGet, endpoint.String(), nil, &apiResponse, &apiException)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiResponse.StatusCode != 200 {
+		return nil, fmt.Errorf("API error: %w", apiException)
+	}
+
+	return apiResponse.DNSRecords, nil
+}
+
+// GetRecordsByHostname returns the records of a single hostname, optionally
+// only those of recordType.
+func (c *Client) GetRecordsByHostname(ctx context.Context, hostname string, recordType string) ([]DNSRecord, error) {
+	hostname = asciiName(hostname)
+
+	endpoint := c.joinUrlPath("dns", "record", hostname)
+	if recordType != "" {
+		endpoint.RawQuery = url.Values{"recordType": {recordType}}.Encode()
+	}
+
 	apiResponse := RecordsResponse{}
 	apiException := APIException{}
-	err := c.doWithCustomError(ctx, http.MethodGet, endpoint.String(), nil, &apiResponse, &apiException)
+	err := c.doWithCustomError(ctx, "GetRecordsByHostname", http.MethodGet, endpoint.String(), nil, &apiResponse, &apiException)
 	if err != nil {
 		return nil, err
 	}
@@ -78,9 +157,6 @@ func (c *Client) GetRecords(ctx context.Context, hostnameId int64) ([]DNSRecord,
 }
 
 func (c *Client) AddOrUpdateRecord(ctx context.Context, hostnameId int64, record DNSRecord, ignoreRecordId bool) (*DNSRecord, error) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
 	urlPaths := []string{"dns", fmt.Sprint(hostnameId), "record"}
 	if record.ID != 0 && !ignoreRecordId {
 		urlPaths = append(urlPaths, fmt.Sprint(record.ID))
@@ -95,7 +171,8 @@ func (c *Client) AddOrUpdateRecord(ctx context.Context, hostnameId int64, record
 
 	apiResponse := DNSRecord{}
 	apiException := APIException{}
-	err = c.doWithCustomError(ctx, http.MethodPost, endpoint.String(), reqBody, &apiResponse, &apiException)
+	create := len(urlPaths) == 3
+	err = c.do(ctx, "AddOrUpdateRecord", http.MethodPost, endpoint.String(), reqBody, &apiResponse, &apiException, !create)
 	if err != nil {
 		return nil, err
 	}
@@ -108,14 +185,106 @@ func (c *Client) AddOrUpdateRecord(ctx context.Context, hostnameId int64, record
 }
 
 func (c *Client) DeleteRecord(ctx context.Context, hostnameId int64, dnsRecordId string) error {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
 	endpoint := c.joinUrlPath("dns", fmt.Sprint(hostnameId), "record", dnsRecordId)
 
 	apiResponse := DeleteResponse{}
 	apiException := APIException{}
-	err := c.doWithCustomError(ctx, http.MethodDelete, endpoint.String(), nil, &apiResponse, &apiException)
+	err := c.doWithCustomError(ctx, "DeleteRecord", http.MethodDelete, endpoint.String(), nil, &apiResponse, &apiException)
+	if err != nil {
+		return err
+	}
+
+	if apiResponse.StatusCode != 200 {
+		return fmt.Errorf("API error: %w", apiException)
+	}
+
+	return nil
+}
+
+func (c *Client) ListDomains(ctx context.Context) ([]Domain, error) {
+	endpoint := c.joinUrlPath("dns")
+
+	apiResponse := DomainsResponse{}
+	apiException := APIException{}
+	err := c.doWithCustomError(ctx, "ListDomains", http.MethodGet, endpoint.String(), nil, &apiResponse, &apiException)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiResponse.StatusCode != 200 {
+		return nil, fmt.Errorf("API error: %w", apiException)
+	}
+
+	return apiResponse.Domains, nil
+}
+
+func (c *Client) GetDomain(ctx context.Context, domainId int64) (*Domain, error) {
+	endpoint := c.joinUrlPath("dns", fmt.Sprint(domainId))
+
+	apiResponse := Domain{}
+	apiException := APIException{}
+	err := c.doWithCustomError(ctx, "GetDomain", http.MethodGet, endpoint.String(), nil, &apiResponse, &apiException)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiResponse.StatusCode != 200 {
+		return nil, fmt.Errorf("API error: %w", apiException)
+	}
+
+	return &apiResponse, nil
+}
+
+func (c *Client) AddDomain(ctx context.Context, domain DomainRequest) (*Domain, error) {
+	endpoint := c.joinUrlPath("dns")
+
+	reqBody, err := json.Marshal(domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request JSON body: %w", err)
+	}
+
+	apiResponse := Domain{}
+	apiException := APIException{}
+	err = c.do(ctx, "AddDomain", http.MethodPost, endpoint.String(), reqBody, &apiResponse, &apiException, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiResponse.StatusCode != 200 {
+		return nil, fmt.Errorf("API error: %w", apiException)
+	}
+
+	return &apiResponse, nil
+}
+
+func (c *Client) UpdateDomain(ctx context.Context, domainId int64, domain DomainRequest) error {
+	endpoint := c.joinUrlPath("dns", fmt.Sprint(domainId))
+
+	reqBody, err := json.Marshal(domain)
+	if err != nil {
+		return fmt.Errorf("failed to create request JSON body: %w", err)
+	}
+
+	apiResponse := UpdateResponse{}
+	apiException := APIException{}
+	err = c.doWithCustomError(ctx, "UpdateDomain", http.MethodPost, endpoint.String(), reqBody, &apiResponse, &apiException)
+	if err != nil {
+		return err
+	}
+
+	if apiResponse.StatusCode != 200 {
+		return fmt.Errorf("API error: %w", apiException)
+	}
+
+	return nil
+}
+
+func (c *Client) DeleteDomain(ctx context.Context, domainId int64) error {
+	endpoint := c.joinUrlPath("dns", fmt.Sprint(domainId))
+
+	apiResponse := DeleteResponse{}
+	apiException := APIException{}
+	err := c.doWithCustomError(ctx, "DeleteDomain", http.MethodDelete, endpoint.String(), nil, &apiResponse, &apiException)
 	if err != nil {
 		return err
 	}
@@ -128,20 +297,91 @@ func (c *Client) DeleteRecord(ctx context.Context, hostnameId int64, dnsRecordId
 }
 
 // exception fields are at the top level of json rather than nested under exception object; parse json again as custom exception object for error logging
-func (c *Client) doWithCustomError(ctx context.Context, method, uri string, body []byte, result any, errorResult any) error {
+func (c *Client) doWithCustomError(ctx context.Context, endpoint, method, uri string, body []byte, result any, errorResult *APIException) error {
+	return c.do(ctx, endpoint, method, uri, body, result, errorResult, true)
+}
+
+// do performs a request with retries; requests that are not idempotent, i.e.
+// creations, are only retried if they never reached Dynu. Every attempt
+// decodes into fresh targets, and only the last one is copied to result and
+// errorResult. The client is not locked while waiting for a retry.
+func (c *Client) do(ctx context.Context, endpoint, method, uri string, body []byte, result any, errorResult *APIException, idempotent bool) error {
+	handler := c.handler()
+	resultType := reflect.TypeOf(result).Elem()
+
+	for attempt := 0; ; attempt++ {
+		header := make(http.Header)
+		header.Set("Accept", "application/json")
+		header.Set("Content-Type", "application/json")
+		header.Set("API-Key", c.APIToken)
+
+		call := &Call{
+			Endpoint: endpoint,
+			Method:   method,
+			URL:      uri,
+			Header:   header,
+			Body:     body,
+			Result:   reflect.New(resultType).Interface(),
+		}
+		if errorResult != nil {
+			call.Exception = &APIException{}
+		}
+
+		if err := c.CircuitBreaker.allow(); err != nil {
+			return err
+		}
+
+		c.mutex.Lock()
+		start := time.Now()
+		err := handler(ctx, call)
+		c.metrics().ObserveRequest(endpoint, callOutcome(call, err), time.Since(start))
+		c.mutex.Unlock()
+
+		failed := isRetryable(ctx, call, err)
+		c.CircuitBreaker.done(failed, ctx.Err() != nil)
+
+		retry := failed && (idempotent || notSent(call, err))
+
+		if !retry || attempt >= c.MaxRetries {
+			reflect.ValueOf(result).Elem().Set(reflect.ValueOf(call.Result).Elem())
+			if errorResult != nil {
+				*errorResult = *call.Exception
+			}
+			return err
+		}
+
+		c.metrics().ObserveRetry(endpoint)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryBaseDelay << attempt):
+		}
+	}
+}
+
+// handler wraps send in the middleware chain, the first middleware being the outermost
+func (c *Client) handler() Handler {
+	handler := Handler(c.send)
+	for i := len(c.Middleware) - 1; i >= 0; i-- {
+		handler = c.Middleware[i](handler)
+	}
+	return handler
+}
+
+// send is the innermost handler making the actual HTTP request
+func (c *Client) send(ctx context.Context, call *Call) error {
 	var reqBody io.Reader
-	if len(body) > 0 {
-		reqBody = bytes.NewReader(body)
+	if len(call.Body) > 0 {
+		reqBody = bytes.NewReader(call.Body)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, uri, reqBody)
+	req, err := http.NewRequestWithContext(ctx, call.Method, call.URL, reqBody)
 	if err != nil {
 		return fmt.Errorf("unable to create request: %w", err)
 	}
 
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("API-Key", c.APIToken)
+	req.Header = call.Header
 
 	resp, err := c.HTTPClient.Do(req)
 	if errors.Is(err, io.EOF) {
@@ -154,19 +394,50 @@ func (c *Client) doWithCustomError(ctx context.Context, method, uri string, body
 
 	defer func() { _ = resp.Body.Close() }()
 
+	call.StatusCode = resp.StatusCode
+
 	raw, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return err
 	}
 
-	err = json.Unmarshal(raw, result)
+	err = json.Unmarshal(raw, call.Result)
 	if err != nil {
 		return err
 	}
 
-	if errorResult != nil {
-		_ = json.Unmarshal(raw, errorResult)
+	if call.Exception != nil {
+		_ = json.Unmarshal(raw, call.Exception)
 	}
 
 	return nil
 }
+
+func callOutcome(call *Call, err error) Outcome {
+	switch {
+	case err != nil:
+		return OutcomeError
+	case call.StatusCode != http.StatusOK:
+		return OutcomeAPIError
+	default:
+		return OutcomeSuccess
+	}
+}
+
+// network errors (no status code), rate limiting and server errors are retried
+func isRetryable(ctx context.Context, call *Call, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil && call.StatusCode == 0 {
+		return true
+	}
+	return call.StatusCode == http.StatusTooManyRequests || call.StatusCode >= 500
+}
+
+// notSent reports whether a request failed before reaching the server, i.e.
+// while connecting, so it can be sent again even if it is not idempotent
+func notSent(call *Call, err error) bool {
+	var opErr *net.OpError
+	return call.StatusCode == 0 && errors.As(err, &opErr) && opErr.Op == "dial"
+}
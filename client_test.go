@@ -0,0 +1,265 @@
+package dynu
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedMetrics struct {
+	mutex    sync.Mutex
+	requests []string
+	retries  int
+}
+
+func (m *recordedMetrics) ObserveRequest(endpoint string, outcome Outcome, _ time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.requests = append(m.requests, endpoint+" "+string(outcome))
+}
+
+func (m *recordedMetrics) ObserveRetry(string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.retries++
+}
+
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := NewClient("token")
+	client.baseURL, _ = url.Parse(server.URL)
+	return client
+}
+
+func TestClientMetricsAndRetries(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"statusCode":503,"type":"Unavailable","message":"try again"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"statusCode":200,"dnsRecords":[]}`))
+	}))
+
+	metrics := &recordedMetrics{}
+	client.Metrics = metrics
+	client.MaxRetries = 1
+
+	_, err := client.GetRecords(context.TODO(), 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 1, metrics.retries)
+	assert.Equal(t, []string{"GetRecords api_error", "GetRecords success"}, metrics.requests)
+}
+
+func TestClientRetryFreshResult(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"statusCode":503,"type":"Unavailable","message":"try again","dnsRecords":[{"id":7}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"statusCode":200}`))
+	}))
+	client.MaxRetries = 1
+
+	// nothing of the failed attempt is left in the result
+	records, err := client.GetRecords(context.TODO(), 1)
+	if assert.NoError(t, err) {
+		assert.Empty(t, records)
+	}
+}
+
+func TestClientRetryReleasesLock(t *testing.T) {
+	failed := make(chan struct{})
+	var once sync.Once
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		first := false
+		if r.URL.Path == "/dns/1/record" {
+			once.Do(func() { first = true })
+		}
+		if first {
+			close(failed)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"statusCode":503}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"statusCode":200,"id":1,"domainName":"my.dynu.com","dnsRecords":[]}`))
+	}))
+	client.MaxRetries = 1
+
+	done := make(chan error)
+	go func() {
+		_, err := client.GetRecords(context.TODO(), 1)
+		done <- err
+	}()
+	<-failed
+
+	// other calls go ahead while GetRecords waits to retry
+	start := time.Now()
+	_, err := client.GetRootDomain(context.TODO(), "my.dynu.com")
+	assert.NoError(t, err)
+	assert.Less(t, time.Since(start), retryBaseDelay)
+	assert.NoError(t, <-done)
+}
+
+func TestClientMiddlewareOrder(t *testing.T) {
+	var header string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Audit")
+		_, _ = w.Write([]byte(`{"statusCode":200,"dnsRecords":[{"id":7,"recordType":"TXT"}]}`))
+	}))
+
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, call *Call) error {
+				order = append(order, name+" before")
+				call.Header.Add("X-Audit", name)
+				err := next(ctx, call)
+				order = append(order, name+" after")
+				return err
+			}
+		}
+	}
+
+	var records []DNSRecord
+	inspect := func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			err := next(ctx, call)
+			records = call.Result.(*RecordsResponse).DNSRecords
+			return err
+		}
+	}
+	client.Middleware = []Middleware{trace("first"), trace("second"), inspect}
+
+	_, err := client.GetRecords(context.TODO(), 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []string{"first before", "second before", "second after", "first after"}, order)
+	assert.Equal(t, "first", header)
+	assert.Len(t, records, 1)
+}
+
+func TestClientMiddlewareShortCircuit(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request should not reach the API")
+	}))
+
+	client.Middleware = []Middleware{func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			call.StatusCode = http.StatusNotFound
+			*call.Exception = APIException{StatusCode: 404, Type: "Not Found", Message: "injected"}
+			return nil
+		}
+	}}
+
+	_, err := client.GetRecords(context.TODO(), 1)
+
+	var apiException APIException
+	if assert.ErrorAs(t, err, &apiException) {
+		assert.Equal(t, "injected", apiException.Message)
+	}
+}
+
+func TestClientDomains(t *testing.T) {
+	fake := newFakeDynu("my.dynu.com")
+	client := newTestClient(t, fake)
+	ctx := context.TODO()
+
+	added, err := client.AddDomain(ctx, DomainRequest{Name: "customer.dynu.com", Group: "customers", Ipv4Address: "203.0.113.1", TTL: 120, Ipv4: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NotZero(t, added.ID)
+	assert.Equal(t, "customer.dynu.com", added.Name)
+
+	request := added.Request()
+	request.Ipv4Address = "203.0.113.2"
+	request.Ipv4WildcardAlias = true
+	if !assert.NoError(t, client.UpdateDomain(ctx, added.ID, request)) {
+		return
+	}
+
+	domain, err := client.GetDomain(ctx, added.ID)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "203.0.113.2", domain.Ipv4Address)
+	assert.Equal(t, "customers", domain.Group)
+	assert.Equal(t, 120, domain.TTL)
+	assert.True(t, domain.Ipv4WildcardAlias)
+
+	domains, err := client.ListDomains(ctx)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, domains, 2)
+
+	if !assert.NoError(t, client.DeleteDomain(ctx, added.ID)) {
+		return
+	}
+
+	_, err = client.GetDomain(ctx, added.ID)
+	var apiException APIException
+	if assert.ErrorAs(t, err, &apiException) {
+		assert.Equal(t, int32(404), apiException.StatusCode)
+	}
+}
+
+func TestClientDoesNotRetryCreates(t *testing.T) {
+	var requests []string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"statusCode":500,"type":"Internal Server Error","message":"failed"}`))
+	}))
+	client.MaxRetries = 1
+
+	// Dynu may have created the record before failing
+	_, err := client.AddOrUpdateRecord(context.TODO(), 1, DNSRecord{Type: "TXT", TextData: "value"}, false)
+	assert.Error(t, err)
+	_, err = client.AddOrUpdateRecord(context.TODO(), 1, DNSRecord{ID: 7, Type: "TXT", TextData: "value"}, true)
+	assert.Error(t, err)
+	_, err = client.AddDomain(context.TODO(), DomainRequest{Name: "example.com"})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"POST /dns/1/record", "POST /dns/1/record", "POST /dns"}, requests)
+
+	// updates are idempotent
+	requests = nil
+	_, err = client.AddOrUpdateRecord(context.TODO(), 1, DNSRecord{ID: 7, Type: "TXT", TextData: "value"}, false)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"POST /dns/1/record/7", "POST /dns/1/record/7"}, requests)
+}
+
+func TestClientRetriesCreatesNotSent(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	client := NewClient("token")
+	client.baseURL, _ = url.Parse(server.URL)
+	metrics := &recordedMetrics{}
+	client.Metrics = metrics
+	client.MaxRetries = 1
+
+	_, err := client.AddOrUpdateRecord(context.TODO(), 1, DNSRecord{Type: "TXT", TextData: "value"}, false)
+	assert.Error(t, err)
+	assert.Equal(t, 1, metrics.retries)
+}
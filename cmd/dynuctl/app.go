@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	dynu "github.com/taviowong/libdns-dynu"
+)
+
+// errUsage is returned for invalid command lines; the usage has been printed already
+var errUsage = errors.New("invalid usage")
+
+type app struct {
+	stdout io.Writer
+	stderr io.Writer
+
+	output    string
+	dryRun    bool
+	ownDomain string
+	client    *dynu.Client
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer, getenv func(string) string) int {
+	a := &app{stdout: stdout, stderr: stderr}
+
+	err := a.run(ctx, args, getenv)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		fmt.Fprintln(stderr, "dynuctl:", err)
+		return 1
+	}
+}
+
+func (a *app) run(ctx context.Context, args []string, getenv func(string) string) error {
+	flags := flag.NewFlagSet("dynuctl", flag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+	flags.Usage = func() {
+		fmt.Fprint(a.stderr, `Usage: dynuctl [flags] <command>
+
+Commands:
+  zones                                          list domains
+  records list <zone>                            list records
+  records get <zone> <id>                        show a record
+  records add <zone> <name> <type> <value>       add a record
+  records set <zone> <id> <name> <type> <value>  update a record
+  records delete <zone> <id>...                  delete records
+  sync <spec-file>                               sync a zone to a YAML/JSON spec
+
+Flags:
+`)
+		flags.PrintDefaults()
+	}
+
+	token := flags.String("token", "", "Dynu API token (default $DYNU_API_TOKEN)")
+	tokenFile := flags.String("token-file", "", "file containing the Dynu API token (default $DYNU_API_TOKEN_FILE)")
+	baseURL := flags.String("base-url", "", "Dynu API base URL")
+	flags.StringVar(&a.ownDomain, "own-domain", "", "Dynu domain owning the records (default the zone)")
+	flags.StringVar(&a.output, "output", "table", "output format: table, json or yaml")
+	flags.BoolVar(&a.dryRun, "dry-run", false, "print changes instead of making them")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return errUsage
+	}
+
+	switch a.output {
+	case "table", "json", "yaml":
+	default:
+		return fmt.Errorf("unknown output format %q", a.output)
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	apiToken, err := readToken(*token, *tokenFile, getenv)
+	if err != nil {
+		return err
+	}
+
+	a.client = dynu.NewClient(apiToken)
+	if *baseURL != "" {
+		if err := a.client.SetBaseURL(*baseURL); err != nil {
+			return err
+		}
+	}
+
+	command, rest := flags.Arg(0), flags.Args()[1:]
+	switch command {
+	case "zones":
+		return a.zones(ctx, rest)
+	case "sync":
+		return a.sync(ctx, rest)
+	case "records":
+		if len(rest) == 0 {
+			flags.Usage()
+			return errUsage
+		}
+		switch rest[0] {
+		case "list":
+			return a.listRecords(ctx, rest[1:])
+		case "get":
+			return a.getRecord(ctx, rest[1:])
+		case "add":
+			return a.addRecord(ctx, rest[1:])
+		case "set":
+			return a.setRecord(ctx, rest[1:])
+		case "delete":
+			return a.deleteRecords(ctx, rest[1:])
+		}
+	}
+
+	flags.Usage()
+	return errUsage
+}
+
+// readToken prefers the flag, then the environment, then the token file
+func readToken(token, tokenFile string, getenv func(string) string) (string, error) {
+	if token != "" {
+		return token, nil
+	}
+	if token := getenv("DYNU_API_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	if tokenFile == "" {
+		tokenFile = getenv("DYNU_API_TOKEN_FILE")
+	}
+	if tokenFile == "" {
+		return "", errors.New("no API token: use -token, -token-file, DYNU_API_TOKEN or DYNU_API_TOKEN_FILE")
+	}
+
+	content, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("read token file: %w", err)
+	}
+
+	token = strings.TrimSpace(string(content))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", tokenFile)
+	}
+	return token, nil
+}
+
+// provider returns a provider for the zone sharing the app's client
+func (a *app) provider(zone string) *dynu.Provider {
+	ownDomain := a.ownDomain
+	if ownDomain == "" {
+		ownDomain = strings.TrimSuffix(zone, ".")
+	}
+	return &dynu.Provider{OwnDomain: ownDomain, Client: a.client}
+}
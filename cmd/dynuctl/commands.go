@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/libdns/libdns"
+	dynu "github.com/taviowong/libdns-dynu"
+)
+
+func (a *app) zones(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return a.usage("zones")
+	}
+
+	domains, err := a.client.ListDomains(ctx)
+	if err != nil {
+		return err
+	}
+
+	rows := make([]zoneRow, 0, len(domains))
+	for _, domain := range domains {
+		rows = append(rows, newZoneRow(domain))
+	}
+	return a.print(rows)
+}
+
+func (a *app) listRecords(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return a.usage("records list <zone>")
+	}
+
+	records, err := a.provider(args[0]).GetRecords(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return a.printRecords(records)
+}
+
+func (a *app) getRecord(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return a.usage("records get <zone> <id>")
+	}
+
+	records, err := a.provider(args[0]).GetRecords(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if record.ID == args[1] {
+			return a.printRecords([]libdns.Record{record})
+		}
+	}
+	return fmt.Errorf("record %s not found in zone %s", args[1], args[0])
+}
+
+func (a *app) addRecord(ctx context.Context, args []string) error {
+	record, zone, err := a.parseRecord("records add <zone> <name> <type> <value>", args, false)
+	if err != nil {
+		return err
+	}
+
+	if a.dryRun {
+		return a.printPlan(ctx, zone, dynu.SyncCreate, []libdns.Record{record})
+	}
+
+	added, err := a.provider(zone).AppendRecords(ctx, zone, []libdns.Record{record})
+	if err != nil {
+		return err
+	}
+	return a.printRecords(added)
+}
+
+func (a *app) setRecord(ctx context.Context, args []string) error {
+	record, zone, err := a.parseRecord("records set <zone> <id> <name> <type> <value>", args, true)
+	if err != nil {
+		return err
+	}
+
+	if a.dryRun {
+		return a.printPlan(ctx, zone, dynu.SyncUpdate, []libdns.Record{record})
+	}
+
+	updated, err := a.provider(zone).SetRecords(ctx, zone, []libdns.Record{record})
+	if err != nil {
+		return err
+	}
+	return a.printRecords(updated)
+}
+
+func (a *app) deleteRecords(ctx context.Context, args []string) error {
+	if len(args) < 2 {
+		return a.usage("records delete <zone> <id>...")
+	}
+
+	zone := args[0]
+	records := make([]libdns.Record, 0, len(args)-1)
+	for _, id := range args[1:] {
+		records = append(records, libdns.Record{ID: id})
+	}
+
+	if a.dryRun {
+		return a.printPlan(ctx, zone, dynu.SyncDelete, records)
+	}
+
+	deleted, err := a.provider(zone).DeleteRecords(ctx, zone, records)
+	if err != nil {
+		return err
+	}
+	return a.printRecords(deleted)
+}
+
+func (a *app) sync(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return a.usage("sync <spec-file>")
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	spec, err := dynu.LoadZoneSpec(file)
+	if err != nil {
+		return err
+	}
+
+	provider := a.provider(spec.Zone)
+	plan, err := provider.PlanSync(ctx, spec)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(a.stdout, plan)
+	if a.dryRun || len(plan.Changes) == 0 {
+		return nil
+	}
+
+	result, err := provider.ApplySync(ctx, plan)
+	fmt.Fprintf(a.stdout, "applied %d change(s), %d failed\n", len(result.Applied), len(result.Failed))
+	return err
+}
+
+// parseRecord parses <zone> [<id>] <name> <type> <value> and the -ttl and -priority flags
+func (a *app) parseRecord(usage string, args []string, withID bool) (libdns.Record, string, error) {
+	flags := flag.NewFlagSet(usage, flag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+	ttl := flags.Duration("ttl", 0, "record TTL; the default of the TTL policy if zero")
+	priority := flags.Uint("priority", 0, "MX record priority")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return libdns.Record{}, "", errUsage
+	}
+
+	want := 4
+	if withID {
+		want = 5
+	}
+	if len(positional) != want {
+		return libdns.Record{}, "", a.usage(usage)
+	}
+
+	zone, fields := positional[0], positional[1:]
+
+	record := libdns.Record{TTL: *ttl, Priority: *priority}
+	if withID {
+		record.ID, fields = fields[0], fields[1:]
+	}
+	record.Name = fields[0]
+	record.Type = strings.ToUpper(fields[1])
+	record.Value = fields[2]
+
+	return record, zone, nil
+}
+
+// parseInterspersed allows flags after positional arguments, e.g. "add zone @ TXT value -ttl 60"
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func (a *app) usage(usage string) error {
+	fmt.Fprintln(a.stderr, "Usage: dynuctl [flags]", usage)
+	return errUsage
+}
+
+// printPlan prints the changes a records command would make, like sync does
+func (a *app) printPlan(ctx context.Context, zone string, action dynu.SyncAction, records []libdns.Record) error {
+	provider := a.provider(zone)
+
+	current := make(map[string]libdns.Record)
+	if action != dynu.SyncCreate {
+		zoneRecords, err := provider.GetRecords(ctx, zone)
+		if err != nil {
+			return err
+		}
+		for _, record := range zoneRecords {
+			current[record.ID] = record
+		}
+	}
+
+	plan := &dynu.SyncPlan{Zone: zone}
+	for _, record := range records {
+		record := record
+		change := dynu.SyncChange{Action: action}
+		if action != dynu.SyncCreate {
+			before, ok := current[record.ID]
+			if !ok {
+				return fmt.Errorf("record %s not found in zone %s", record.ID, zone)
+			}
+			change.Before = &before
+		}
+		if action != dynu.SyncDelete {
+			ttl, err := provider.TTLPolicy.Apply(record.TTL)
+			if err != nil {
+				return fmt.Errorf("record %s %s: %w", record.Name, record.Type, err)
+			}
+			record.TTL = ttl
+			change.After = &record
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	fmt.Fprint(a.stdout, plan)
+	return nil
+}
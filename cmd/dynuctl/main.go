@@ -0,0 +1,24 @@
+// Command dynuctl manages Dynu DNS records from the command line.
+//
+// Usage:
+//
+//	dynuctl [flags] zones
+//	dynuctl [flags] records list <zone>
+//	dynuctl [flags] records get <zone> <id>
+//	dynuctl [flags] records add <zone> <name> <type> <value> [-ttl 120] [-priority 10]
+//	dynuctl [flags] records set <zone> <id> <name> <type> <value> [-ttl 120] [-priority 10]
+//	dynuctl [flags] records delete <zone> <id>...
+//	dynuctl [flags] sync <spec-file>
+//
+// The API token is read from -token, the DYNU_API_TOKEN environment
+// variable, or the file named by -token-file or DYNU_API_TOKEN_FILE.
+package main
+
+import (
+	"context"
+	"os"
+)
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr, os.Getenv))
+}
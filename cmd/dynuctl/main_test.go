@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	dynu "github.com/taviowong/libdns-dynu"
+	"gopkg.in/yaml.v3"
+)
+
+// fakeAPI serves a single domain "example.com" with id 100
+type fakeAPI struct {
+	mutex    sync.Mutex
+	records  []dynu.DNSRecord
+	nextID   int64
+	requests []string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if r.Header.Get("API-Key") != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"statusCode":401,"type":"Authentication Exception","message":"Invalid API key"}`))
+		return
+	}
+
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	switch {
+	case r.URL.Path == "/dns":
+		_ = json.NewEncoder(w).Encode(dynu.DomainsResponse{StatusCode: 200, Domains: []dynu.Domain{{ID: 100, Name: "example.com", TTL: 90, State: "Complete"}}})
+	case r.URL.Path == "/dns/getroot/example.com":
+		_ = json.NewEncoder(w).Encode(dynu.DNSHostname{StatusCode: 200, ID: 100, DomainName: "example.com", Hostname: "example.com"})
+	case r.URL.Path == "/dns/100/record" && r.Method == http.MethodGet:
+		_ = json.NewEncoder(w).Encode(dynu.RecordsResponse{StatusCode: 200, DNSRecords: f.records})
+	case strings.HasPrefix(r.URL.Path, "/dns/100/record") && r.Method == http.MethodPost:
+		var record dynu.DNSRecord
+		_ = json.NewDecoder(r.Body).Decode(&record)
+		record.Hostname = "example.com"
+		if record.NodeName != "" {
+			record.Hostname = record.NodeName + ".example.com"
+		}
+
+		// POST /dns/100/record/{id} updates the record
+		if rawID := strings.TrimPrefix(r.URL.Path, "/dns/100/record/"); rawID != r.URL.Path {
+			record.ID, _ = strconv.ParseInt(rawID, 10, 64)
+			for i := range f.records {
+				if f.records[i].ID == record.ID {
+					f.records[i] = record
+					record.StatusCode = 200
+					_ = json.NewEncoder(w).Encode(record)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"statusCode":404,"type":"Not Found","message":"record not found"}`))
+			return
+		}
+
+		record.ID = f.nextID
+		f.nextID++
+		f.records = append(f.records, record)
+		record.StatusCode = 200
+		_ = json.NewEncoder(w).Encode(record)
+	case strings.HasPrefix(r.URL.Path, "/dns/100/record/") && r.Method == http.MethodDelete:
+		id, _ := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/dns/100/record/"), 10, 64)
+		for i, record := range f.records {
+			if record.ID == id {
+				f.records = append(f.records[:i], f.records[i+1:]...)
+				_, _ = w.Write([]byte(`{"statusCode":200}`))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"statusCode":404,"type":"Not Found","message":"record not found"}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"statusCode":404,"type":"Not Found","message":"unknown endpoint"}`))
+	}
+}
+
+func newFakeAPI(t *testing.T) (*fakeAPI, string) {
+	fake := &fakeAPI{
+		nextID: 11,
+		records: []dynu.DNSRecord{
+			{ID: 10, Type: "A", NodeName: "www", Hostname: "www.example.com", Ipv4Address: "203.0.113.1", TTL: 300, State: true},
+		},
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server.URL
+}
+
+func runCommand(t *testing.T, env map[string]string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.TODO(), args, &stdout, &stderr, func(key string) string { return env[key] })
+	return code, stdout.String(), stderr.String()
+}
+
+func TestListRecordsTable(t *testing.T) {
+	_, baseURL := newFakeAPI(t)
+
+	code, stdout, stderr := runCommand(t, map[string]string{"DYNU_API_TOKEN": "secret"}, "-base-url", baseURL, "records", "list", "example.com.")
+	if !assert.Equal(t, 0, code, stderr) {
+		return
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, []string{"ID", "TYPE", "NAME", "VALUE", "TTL", "PRIORITY"}, strings.Fields(lines[0]))
+		assert.Equal(t, []string{"10", "A", "www", "203.0.113.1", "300", "0"}, strings.Fields(lines[1]))
+	}
+}
+
+func TestZonesJSON(t *testing.T) {
+	_, baseURL := newFakeAPI(t)
+
+	code, stdout, stderr := runCommand(t, map[string]string{"DYNU_API_TOKEN": "secret"}, "-base-url", baseURL, "-output", "json", "zones")
+	if !assert.Equal(t, 0, code, stderr) {
+		return
+	}
+
+	var zones []zoneRow
+	if assert.NoError(t, json.Unmarshal([]byte(stdout), &zones)) && assert.Len(t, zones, 1) {
+		assert.Equal(t, "example.com", zones[0].Name)
+		assert.Equal(t, int64(100), zones[0].ID)
+	}
+}
+
+func TestAddGetAndDeleteRecordYAML(t *testing.T) {
+	fake, baseURL := newFakeAPI(t)
+	env := map[string]string{"DYNU_API_TOKEN": "secret"}
+
+	code, stdout, stderr := runCommand(t, env, "-base-url", baseURL, "-output", "yaml", "records", "add", "example.com", "_acme-challenge", "txt", "token value", "-ttl", "60s")
+	if !assert.Equal(t, 0, code, stderr) {
+		return
+	}
+
+	var added []recordRow
+	if !assert.NoError(t, yaml.Unmarshal([]byte(stdout), &added)) || !assert.Len(t, added, 1) {
+		return
+	}
+	assert.Equal(t, recordRow{ID: "11", Type: "TXT", Name: "_acme-challenge", Value: "token value", TTL: 60}, added[0])
+
+	code, stdout, stderr = runCommand(t, env, "-base-url", baseURL, "-output", "json", "records", "get", "example.com", "11")
+	if assert.Equal(t, 0, code, stderr) {
+		assert.Contains(t, stdout, `"value": "token value"`)
+	}
+
+	code, _, stderr = runCommand(t, env, "-base-url", baseURL, "records", "delete", "example.com", "11")
+	assert.Equal(t, 0, code, stderr)
+	assert.Len(t, fake.records, 1)
+}
+
+func TestSetRecordKeepsID(t *testing.T) {
+	fake, baseURL := newFakeAPI(t)
+
+	code, stdout, stderr := runCommand(t, map[string]string{"DYNU_API_TOKEN": "secret"}, "-base-url", baseURL, "-output", "json", "records", "set", "example.com", "10", "www", "A", "203.0.113.9", "-ttl", "120s")
+	if !assert.Equal(t, 0, code, stderr) {
+		return
+	}
+
+	var set []recordRow
+	if assert.NoError(t, json.Unmarshal([]byte(stdout), &set)) && assert.Len(t, set, 1) {
+		assert.Equal(t, recordRow{ID: "10", Type: "A", Name: "www", Value: "203.0.113.9", TTL: 120}, set[0])
+	}
+	if assert.Len(t, fake.records, 1) {
+		assert.Equal(t, int64(10), fake.records[0].ID)
+		assert.Equal(t, "203.0.113.9", fake.records[0].Ipv4Address)
+	}
+	assert.Contains(t, fake.requests, "POST /dns/100/record/10")
+}
+
+func TestDryRunMakesNoChanges(t *testing.T) {
+	fake, baseURL := newFakeAPI(t)
+
+	env := map[string]string{"DYNU_API_TOKEN": "secret"}
+
+	code, stdout, stderr := runCommand(t, env, "-base-url", baseURL, "-dry-run", "records", "delete", "example.com", "10")
+	if assert.Equal(t, 0, code, stderr) {
+		assert.Equal(t, "- www A \"203.0.113.1\" (ttl 5m0s)\nzone example.com: 0 to create, 0 to update, 1 to delete\n", stdout)
+	}
+
+	code, stdout, stderr = runCommand(t, env, "-base-url", baseURL, "-dry-run", "records", "set", "example.com", "10", "www", "A", "203.0.113.9", "-ttl", "1m")
+	if assert.Equal(t, 0, code, stderr) {
+		assert.Equal(t, "~ www A \"203.0.113.1\" (ttl 5m0s) => \"203.0.113.9\" (ttl 1m0s)\nzone example.com: 0 to create, 1 to update, 0 to delete\n", stdout)
+	}
+
+	// without -ttl, the default of the TTL policy applies as in the library
+	code, stdout, stderr = runCommand(t, env, "-base-url", baseURL, "-dry-run", "records", "add", "example.com", "api", "A", "203.0.113.2")
+	if assert.Equal(t, 0, code, stderr) {
+		assert.Equal(t, "+ api A \"203.0.113.2\" (ttl 5m0s)\nzone example.com: 1 to create, 0 to update, 0 to delete\n", stdout)
+	}
+
+	code, _, stderr = runCommand(t, env, "-base-url", baseURL, "-dry-run", "records", "delete", "example.com", "99")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "record 99 not found in zone example.com")
+
+	for _, request := range fake.requests {
+		assert.True(t, strings.HasPrefix(request, "GET "), request)
+	}
+	assert.Len(t, fake.records, 1)
+}
+
+func TestTokenFile(t *testing.T) {
+	_, baseURL := newFakeAPI(t)
+
+	path := filepath.Join(t.TempDir(), "token")
+	if !assert.NoError(t, os.WriteFile(path, []byte("secret\n"), 0o600)) {
+		return
+	}
+
+	code, _, stderr := runCommand(t, map[string]string{"DYNU_API_TOKEN_FILE": path}, "-base-url", baseURL, "zones")
+	assert.Equal(t, 0, code, stderr)
+
+	code, _, stderr = runCommand(t, nil, "-base-url", baseURL, "zones")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "no API token")
+}
+
+func TestUsageErrors(t *testing.T) {
+	env := map[string]string{"DYNU_API_TOKEN": "secret"}
+
+	code, _, _ := runCommand(t, env)
+	assert.Equal(t, 2, code)
+
+	code, _, _ = runCommand(t, env, "records", "add", "example.com", "www")
+	assert.Equal(t, 2, code)
+
+	code, _, stderr := runCommand(t, env, "-output", "xml", "zones")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "unknown output format")
+}
+
+func TestSync(t *testing.T) {
+	fake, baseURL := newFakeAPI(t)
+	env := map[string]string{"DYNU_API_TOKEN": "secret"}
+
+	path := filepath.Join(t.TempDir(), "example.com.yaml")
+	spec := "zone: example.com.\nttl: 300\nrecords:\n  - {name: www, type: A, value: 203.0.113.1}\n  - {name: api, type: A, value: 203.0.113.2}\n"
+	if !assert.NoError(t, os.WriteFile(path, []byte(spec), 0o600)) {
+		return
+	}
+
+	code, stdout, stderr := runCommand(t, env, "-base-url", baseURL, "-dry-run", "sync", path)
+	if !assert.Equal(t, 0, code, stderr) {
+		return
+	}
+	assert.Equal(t, "+ api A \"203.0.113.2\" (ttl 5m0s)\nzone example.com.: 1 to create, 0 to update, 0 to delete\n", stdout)
+	assert.Len(t, fake.records, 1)
+
+	code, stdout, stderr = runCommand(t, env, "-base-url", baseURL, "sync", path)
+	if assert.Equal(t, 0, code, stderr) {
+		assert.Contains(t, stdout, "applied 1 change(s), 0 failed")
+	}
+	assert.Len(t, fake.records, 2)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/libdns/libdns"
+	dynu "github.com/taviowong/libdns-dynu"
+	"gopkg.in/yaml.v3"
+)
+
+type recordRow struct {
+	ID       string `json:"id" yaml:"id"`
+	Type     string `json:"type" yaml:"type"`
+	Name     string `json:"name" yaml:"name"`
+	Value    string `json:"value" yaml:"value"`
+	TTL      int    `json:"ttl" yaml:"ttl"`
+	Priority uint   `json:"priority,omitempty" yaml:"priority,omitempty"`
+}
+
+func newRecordRow(record libdns.Record) recordRow {
+	return recordRow{
+		ID:       record.ID,
+		Type:     record.Type,
+		Name:     record.Name,
+		Value:    record.Value,
+		TTL:      int(record.TTL.Seconds()),
+		Priority: record.Priority,
+	}
+}
+
+type zoneRow struct {
+	ID    int64  `json:"id" yaml:"id"`
+	Name  string `json:"name" yaml:"name"`
+	Group string `json:"group,omitempty" yaml:"group,omitempty"`
+	IPv4  string `json:"ipv4Address,omitempty" yaml:"ipv4Address,omitempty"`
+	IPv6  string `json:"ipv6Address,omitempty" yaml:"ipv6Address,omitempty"`
+	TTL   int    `json:"ttl" yaml:"ttl"`
+	State string `json:"state,omitempty" yaml:"state,omitempty"`
+}
+
+func newZoneRow(domain dynu.Domain) zoneRow {
+	return zoneRow{
+		ID:    domain.ID,
+		Name:  domain.Name,
+		Group: domain.Group,
+		IPv4:  domain.Ipv4Address,
+		IPv6:  domain.Ipv6Address,
+		TTL:   domain.TTL,
+		State: domain.State,
+	}
+}
+
+func (a *app) printRecords(records []libdns.Record) error {
+	rows := make([]recordRow, 0, len(records))
+	for _, record := range records {
+		rows = append(rows, newRecordRow(record))
+	}
+	return a.print(rows)
+}
+
+// print writes a slice of row structs in the selected output format
+func (a *app) print(rows any) error {
+	switch a.output {
+	case "json":
+		encoder := json.NewEncoder(a.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	case "yaml":
+		encoder := yaml.NewEncoder(a.stdout)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(rows); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return a.printTable(rows)
+	}
+}
+
+// printTable uses the json tags as column headers
+func (a *app) printTable(rows any) error {
+	writer := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+
+	value := reflect.ValueOf(rows)
+	rowType := value.Type().Elem()
+
+	headers := make([]string, rowType.NumField())
+	for i := range headers {
+		name, _, _ := strings.Cut(rowType.Field(i).Tag.Get("json"), ",")
+		headers[i] = strings.ToUpper(name)
+	}
+	fmt.Fprintln(writer, strings.Join(headers, "\t"))
+
+	for i := 0; i < value.Len(); i++ {
+		row := value.Index(i)
+		cells := make([]string, row.NumField())
+		for j := range cells {
+			cells[j] = fmt.Sprint(row.Field(j).Interface())
+		}
+		fmt.Fprintln(writer, strings.Join(cells, "\t"))
+	}
+
+	return writer.Flush()
+}
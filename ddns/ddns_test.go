@@ -0,0 +1,216 @@
+package ddns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	dynu "github.com/taviowong/libdns-dynu"
+)
+
+type staticDetector struct {
+	addresses Addresses
+}
+
+func (d *staticDetector) Detect(context.Context) (Addresses, error) {
+	return d.addresses, nil
+}
+
+type recordingSetter struct {
+	updates []Addresses
+	err     error
+}
+
+func (s *recordingSetter) SetAddresses(_ context.Context, _ string, addresses Addresses) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.updates = append(s.updates, addresses)
+	return nil
+}
+
+func TestUpdaterSkipsUnchangedAddresses(t *testing.T) {
+	detector := &staticDetector{Addresses{IPv4: netip.MustParseAddr("203.0.113.1")}}
+	setter := &recordingSetter{}
+	updater := &Updater{Hostname: "my.dynu.com", Detector: detector, Setter: setter}
+	ctx := context.TODO()
+
+	changed, err := updater.Update(ctx)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	changed, err = updater.Update(ctx)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	detector.addresses.IPv4 = netip.MustParseAddr("203.0.113.2")
+	changed, err = updater.Update(ctx)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	assert.Len(t, setter.updates, 2)
+	assert.Equal(t, detector.addresses, updater.Last())
+}
+
+func TestUpdaterRunStopsOnFatalError(t *testing.T) {
+	detector := &staticDetector{Addresses{IPv4: netip.MustParseAddr("203.0.113.1")}}
+	setter := &recordingSetter{err: NICUpdateError("badauth")}
+
+	var errs []error
+	updater := &Updater{
+		Hostname: "my.dynu.com",
+		Detector: detector,
+		Setter:   setter,
+		OnError:  func(err error) { errs = append(errs, err) },
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	err := updater.Run(ctx)
+	var nicErr NICUpdateError
+	assert.ErrorAs(t, err, &nicErr)
+	assert.Len(t, errs, 1)
+}
+
+func TestUpdaterBackoff(t *testing.T) {
+	updater := &Updater{Interval: time.Minute, MinBackoff: 10 * time.Second}
+
+	assert.Equal(t, 10*time.Second, updater.backoff(0))
+	assert.Equal(t, 20*time.Second, updater.backoff(1))
+	assert.Equal(t, 40*time.Second, updater.backoff(2))
+	assert.Equal(t, time.Minute, updater.backoff(3))
+	assert.Equal(t, time.Minute, updater.backoff(30))
+}
+
+func TestHTTPDetector(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4":
+			_, _ = w.Write([]byte("203.0.113.7\n"))
+		case "/v6":
+			_, _ = w.Write([]byte("2001:db8::7"))
+		default:
+			_, _ = w.Write([]byte("2001:db8::7"))
+		}
+	}))
+	defer server.Close()
+
+	detector := &HTTPDetector{IPv4URL: server.URL + "/v4", IPv6URL: server.URL + "/v6"}
+	addresses, err := detector.Detect(context.TODO())
+	if assert.NoError(t, err) {
+		assert.Equal(t, netip.MustParseAddr("203.0.113.7"), addresses.IPv4)
+		assert.Equal(t, netip.MustParseAddr("2001:db8::7"), addresses.IPv6)
+	}
+
+	// an IPv6 answer from the IPv4 service is rejected
+	detector = &HTTPDetector{IPv4URL: server.URL + "/wrong", IPv6URL: "-"}
+	_, err = detector.Detect(context.TODO())
+	assert.Error(t, err)
+}
+
+func TestNICUpdate(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		if username == "down" {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("<html><body>badauth gateway</body></html>"))
+			return
+		}
+		if username != "user" || password != "secret" {
+			_, _ = w.Write([]byte("badauth"))
+			return
+		}
+		query = r.URL.Query()
+		_, _ = w.Write([]byte("good 203.0.113.1"))
+	}))
+	defer server.Close()
+
+	setter := &NICUpdate{Username: "user", Password: "secret", URL: server.URL}
+	err := setter.SetAddresses(context.TODO(), "my.dynu.com", Addresses{IPv4: netip.MustParseAddr("203.0.113.1")})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "my.dynu.com", query.Get("hostname"))
+		assert.Equal(t, "203.0.113.1", query.Get("myip"))
+		assert.Equal(t, "no", query.Get("myipv6"))
+	}
+
+	setter.Password = "wrong"
+	err = setter.SetAddresses(context.TODO(), "my.dynu.com", Addresses{IPv4: netip.MustParseAddr("203.0.113.1")})
+	var nicErr NICUpdateError
+	if assert.ErrorAs(t, err, &nicErr) {
+		assert.True(t, nicErr.Fatal())
+	}
+
+	// an error page is not a return code
+	setter.Username = "down"
+	err = setter.SetAddresses(context.TODO(), "my.dynu.com", Addresses{IPv4: netip.MustParseAddr("203.0.113.1")})
+	assert.EqualError(t, err, "nic/update my.dynu.com: HTTP status 502")
+	assert.False(t, errors.As(err, &nicErr))
+}
+
+// redirect sends all requests to a test server
+type redirect struct {
+	server *httptest.Server
+}
+
+func (r redirect) RoundTrip(req *http.Request) (*http.Response, error) {
+	target, _ := url.Parse(r.server.URL)
+	req = req.Clone(req.Context())
+	req.URL.Scheme = target.Scheme
+	req.URL.Host = target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestDomainAPI(t *testing.T) {
+	domain := dynu.Domain{ID: 100, Name: "my.dynu.com", Group: "home", Ipv4Address: "203.0.113.1", TTL: 90, Ipv4: true, Ipv6: false}
+	updates := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/dns/getroot/my.dynu.com":
+			_ = json.NewEncoder(w).Encode(dynu.DNSHostname{StatusCode: 200, ID: domain.ID, DomainName: domain.Name, Hostname: domain.Name})
+		case r.URL.Path == "/v2/dns/100" && r.Method == http.MethodGet:
+			response := domain
+			response.StatusCode = 200
+			_ = json.NewEncoder(w).Encode(response)
+		case r.URL.Path == "/v2/dns/100" && r.Method == http.MethodPost:
+			var request dynu.DomainRequest
+			_ = json.NewDecoder(r.Body).Decode(&request)
+			domain.Ipv4Address = request.Ipv4Address
+			domain.Ipv6Address = request.Ipv6Address
+			domain.Ipv4, domain.Ipv6 = request.Ipv4, request.Ipv6
+			domain.Group = request.Group
+			updates++
+			_, _ = w.Write([]byte(`{"statusCode":200}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"statusCode":404,"type":"Not Found","message":"not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := dynu.NewClient("token")
+	client.HTTPClient.Transport = redirect{server}
+	setter := &DomainAPI{Client: client}
+
+	addresses := Addresses{IPv4: netip.MustParseAddr("203.0.113.9"), IPv6: netip.MustParseAddr("2001:db8::9")}
+	if !assert.NoError(t, setter.SetAddresses(context.TODO(), "my.dynu.com", addresses)) {
+		return
+	}
+	assert.Equal(t, "203.0.113.9", domain.Ipv4Address)
+	assert.Equal(t, "2001:db8::9", domain.Ipv6Address)
+	assert.True(t, domain.Ipv6, "IPv6 must be enabled to serve the new address")
+	assert.True(t, domain.Ipv4)
+	assert.Equal(t, "home", domain.Group, "other settings must be kept")
+
+	// unchanged addresses are not sent
+	assert.NoError(t, setter.SetAddresses(context.TODO(), "my.dynu.com", addresses))
+	assert.Equal(t, 1, updates)
+}
@@ -0,0 +1,177 @@
+package ddns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// Addresses are the public addresses of a host; an invalid (zero) address
+// means the address family is not available.
+type Addresses struct {
+	IPv4 netip.Addr
+	IPv6 netip.Addr
+}
+
+func (a Addresses) String() string {
+	return fmt.Sprintf("ipv4=%s ipv6=%s", a.IPv4, a.IPv6)
+}
+
+// Detector finds the public addresses of this host.
+type Detector interface {
+	Detect(ctx context.Context) (Addresses, error)
+}
+
+const (
+	defaultIPv4EchoURL = "https://api4.ipify.org"
+	defaultIPv6EchoURL = "https://api6.ipify.org"
+)
+
+// HTTPDetector asks echo services that answer with the caller's address as
+// plain text.
+type HTTPDetector struct {
+	HTTPClient *http.Client
+	// IPv4URL and IPv6URL default to ipify; set one to "-" to skip that family.
+	IPv4URL string
+	IPv6URL string
+}
+
+// Detect implements Detector. It fails only if no address could be detected.
+func (d *HTTPDetector) Detect(ctx context.Context) (Addresses, error) {
+	var addresses Addresses
+	var errs []error
+
+	ipv4URL := d.IPv4URL
+	if ipv4URL == "" {
+		ipv4URL = defaultIPv4EchoURL
+	}
+	ipv6URL := d.IPv6URL
+	if ipv6URL == "" {
+		ipv6URL = defaultIPv6EchoURL
+	}
+
+	if ipv4URL != "-" {
+		addr, err := d.echo(ctx, ipv4URL)
+		if err == nil && !addr.Is4() {
+			err = fmt.Errorf("%s returned %s which is not an IPv4 address", ipv4URL, addr)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			addresses.IPv4 = addr
+		}
+	}
+
+	if ipv6URL != "-" {
+		addr, err := d.echo(ctx, ipv6URL)
+		if err == nil && (!addr.Is6() || addr.Is4In6()) {
+			err = fmt.Errorf("%s returned %s which is not an IPv6 address", ipv6URL, addr)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			addresses.IPv6 = addr
+		}
+	}
+
+	if !addresses.IPv4.IsValid() && !addresses.IPv6.IsValid() {
+		return addresses, errors.Join(append([]error{errors.New("no public address detected")}, errs...)...)
+	}
+	return addresses, nil
+}
+
+func (d *HTTPDetector) echo(ctx context.Context, url string) (netip.Addr, error) {
+	client := d.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return netip.Addr{}, fmt.Errorf("%s: HTTP status %d", url, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSpace(string(body)))
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("%s: %w", url, err)
+	}
+	return addr, nil
+}
+
+// InterfaceDetector uses the first global unicast, non-private addresses
+// assigned to the local network interfaces, for hosts with a public address.
+type InterfaceDetector struct {
+	// Interface restricts detection to the named interface, e.g. "eth0".
+	Interface string
+}
+
+// Detect implements Detector.
+func (d *InterfaceDetector) Detect(_ context.Context) (Addresses, error) {
+	var addresses Addresses
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return addresses, err
+	}
+
+	for _, iface := range interfaces {
+		if d.Interface != "" && iface.Name != d.Interface {
+			continue
+		}
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+
+		ifaceAddrs, err := iface.Addrs()
+		if err != nil {
+			return addresses, fmt.Errorf("interface %s: %w", iface.Name, err)
+		}
+
+		for _, ifaceAddr := range ifaceAddrs {
+			prefix, err := netip.ParsePrefix(ifaceAddr.String())
+			if err != nil {
+				continue
+			}
+			addr := prefix.Addr().Unmap()
+			if !isPublic(addr) {
+				continue
+			}
+			if addr.Is4() && !addresses.IPv4.IsValid() {
+				addresses.IPv4 = addr
+			}
+			if addr.Is6() && !addresses.IPv6.IsValid() {
+				addresses.IPv6 = addr
+			}
+		}
+	}
+
+	if !addresses.IPv4.IsValid() && !addresses.IPv6.IsValid() {
+		return addresses, errors.New("no public address found on local interfaces")
+	}
+	return addresses, nil
+}
+
+func isPublic(addr netip.Addr) bool {
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
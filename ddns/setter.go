@@ -0,0 +1,158 @@
+package ddns
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	dynu "github.com/taviowong/libdns-dynu"
+)
+
+// Setter sets the addresses of a Dynu hostname.
+type Setter interface {
+	SetAddresses(ctx context.Context, hostname string, addresses Addresses) error
+}
+
+// DomainAPI sets addresses with the Dynu domain update API. The hostname must
+// be a domain in the Dynu account, not a node (record) of one.
+type DomainAPI struct {
+	Client *dynu.Client
+}
+
+// SetAddresses implements Setter. Valid addresses are also enabled, so they
+// are served; invalid addresses are left unchanged.
+func (s *DomainAPI) SetAddresses(ctx context.Context, hostname string, addresses Addresses) error {
+	// GET /dns/getroot/{hostname}
+	root, err := s.Client.GetRootDomain(ctx, hostname)
+	if err != nil {
+		return err
+	}
+	if root.Node != "" {
+		return fmt.Errorf("%s is node %q of domain %s, not a domain", hostname, root.Node, root.DomainName)
+	}
+
+	// GET /dns/{id}
+	domain, err := s.Client.GetDomain(ctx, root.ID)
+	if err != nil {
+		return err
+	}
+
+	request := domain.Request()
+	if addresses.IPv4.IsValid() {
+		request.Ipv4Address, request.Ipv4 = addresses.IPv4.String(), true
+	}
+	if addresses.IPv6.IsValid() {
+		request.Ipv6Address, request.Ipv6 = addresses.IPv6.String(), true
+	}
+	if request == domain.Request() {
+		return nil
+	}
+
+	// POST /dns/{id}
+	return s.Client.UpdateDomain(ctx, domain.ID, request)
+}
+
+const defaultNICUpdateURL = "https://api.dynu.com/nic/update"
+
+// NICUpdate sets addresses with the legacy dyndns2 compatible nic/update
+// protocol used by IP update clients and routers.
+type NICUpdate struct {
+	Username string
+	// Password is the account or IP update password, in plain text or as
+	// MD5/SHA256 hex digest.
+	Password   string
+	HTTPClient *http.Client
+	// URL defaults to https://api.dynu.com/nic/update.
+	URL string
+}
+
+// SetAddresses implements Setter.
+func (s *NICUpdate) SetAddresses(ctx context.Context, hostname string, addresses Addresses) error {
+	endpoint := s.URL
+	if endpoint == "" {
+		endpoint = defaultNICUpdateURL
+	}
+
+	query := url.Values{}
+	query.Set("hostname", hostname)
+	if addresses.IPv4.IsValid() {
+		query.Set("myip", addresses.IPv4.String())
+	} else {
+		query.Set("myip", "no")
+	}
+	if addresses.IPv6.IsValid() {
+		query.Set("myipv6", addresses.IPv6.String())
+	} else {
+		query.Set("myipv6", "no")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+	req.SetBasicAuth(s.Username, s.Password)
+
+	client := s.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// error pages carry no return codes
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("nic/update %s: HTTP status %d", hostname, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return err
+	}
+
+	// one line per hostname, e.g. "good 203.0.113.1" or "nochg"
+	for _, line := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+		code := strings.Fields(line)
+		if len(code) == 0 {
+			continue
+		}
+		switch code[0] {
+		case "good", "nochg":
+		default:
+			return fmt.Errorf("nic/update %s: %w", hostname, NICUpdateError(strings.TrimSpace(line)))
+		}
+	}
+	return nil
+}
+
+// NICUpdateError is a return code of the nic/update protocol other than
+// "good" or "nochg", e.g. "badauth", "nohost" or "abuse".
+type NICUpdateError string
+
+func (e NICUpdateError) Error() string {
+	return "update failed: " + string(e)
+}
+
+// Fatal reports whether retrying cannot help, e.g. on wrong credentials.
+func (e NICUpdateError) Fatal() bool {
+	code, _, _ := strings.Cut(string(e), " ")
+	switch code {
+	case "badauth", "nohost", "notfqdn", "abuse", "numhost":
+		return true
+	}
+	return false
+}
+
+// Interface guards
+var (
+	_ Setter = (*DomainAPI)(nil)
+	_ Setter = (*NICUpdate)(nil)
+	_ error  = NICUpdateError("")
+)
@@ -0,0 +1,132 @@
+// Package ddns keeps the addresses of a Dynu hostname up to date with the
+// public addresses of the host it runs on.
+package ddns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultInterval   = 5 * time.Minute
+	defaultMinBackoff = 10 * time.Second
+)
+
+// Updater detects the public addresses and sends them to Dynu when they
+// changed since the last successful update.
+type Updater struct {
+	Hostname string
+	Detector Detector
+	Setter   Setter
+	// DisableIPv4 and DisableIPv6 keep the address family from being sent.
+	DisableIPv4 bool
+	DisableIPv6 bool
+
+	// Interval between checks in Run; five minutes by default.
+	Interval time.Duration
+	// MinBackoff is the wait after the first failure in Run, doubled for every
+	// further failure up to Interval; ten seconds by default.
+	MinBackoff time.Duration
+	// OnError, if set, is called with every error in Run.
+	OnError func(error)
+
+	mutex sync.Mutex
+	last  Addresses
+}
+
+// Update sends the current addresses if they changed and reports whether
+// they were sent.
+func (u *Updater) Update(ctx context.Context) (bool, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	addresses, err := u.Detector.Detect(ctx)
+	if err != nil {
+		return false, fmt.Errorf("detect addresses: %w", err)
+	}
+
+	if u.DisableIPv4 {
+		addresses.IPv4 = u.last.IPv4
+	}
+	if u.DisableIPv6 {
+		addresses.IPv6 = u.last.IPv6
+	}
+	if !addresses.IPv4.IsValid() && !addresses.IPv6.IsValid() {
+		return false, errors.New("no address to update")
+	}
+	if addresses == u.last {
+		return false, nil
+	}
+
+	if err := u.Setter.SetAddresses(ctx, u.Hostname, addresses); err != nil {
+		return false, fmt.Errorf("update %s to %s: %w", u.Hostname, addresses, err)
+	}
+
+	u.last = addresses
+	return true, nil
+}
+
+// Last returns the addresses of the last successful update.
+func (u *Updater) Last() Addresses {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	return u.last
+}
+
+// Run calls Update every Interval until ctx is done, backing off on failures.
+// It stops early on errors that retrying cannot fix, e.g. bad credentials.
+func (u *Updater) Run(ctx context.Context) error {
+	failures := 0
+
+	for {
+		_, err := u.Update(ctx)
+
+		wait := u.interval()
+		if err != nil {
+			if u.OnError != nil {
+				u.OnError(err)
+			}
+
+			var nicErr NICUpdateError
+			if errors.As(err, &nicErr) && nicErr.Fatal() {
+				return err
+			}
+
+			wait = u.backoff(failures)
+			failures++
+		} else {
+			failures = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (u *Updater) interval() time.Duration {
+	if u.Interval <= 0 {
+		return defaultInterval
+	}
+	return u.Interval
+}
+
+func (u *Updater) backoff(failures int) time.Duration {
+	backoff := u.MinBackoff
+	if backoff <= 0 {
+		backoff = defaultMinBackoff
+	}
+	for i := 0; i < failures && backoff < u.interval(); i++ {
+		backoff *= 2
+	}
+	if backoff > u.interval() {
+		return u.interval()
+	}
+	return backoff
+}
@@ -0,0 +1,147 @@
+package dynu
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// FieldChange is a changed field of a record.
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// RecordChange is a record present on both sides with different fields.
+type RecordChange struct {
+	Old    libdns.Record
+	New    libdns.Record
+	Fields []FieldChange
+}
+
+// RecordDiff is the difference between two record sets.
+type RecordDiff struct {
+	Add    []libdns.Record
+	Change []RecordChange
+	Remove []libdns.Record
+}
+
+// Empty reports whether both record sets are equivalent.
+func (d RecordDiff) Empty() bool {
+	return len(d.Add) == 0 && len(d.Change) == 0 && len(d.Remove) == 0
+}
+
+// DiffRecords compares the records of zone, e.g. "what Dynu has" as returned
+// by Provider.GetRecords against "what we want". Records are matched by ID
+// when the wanted record has one, otherwise by name, type and value. Names
+// may be relative, "@" or absolute; they are compared relative to the zone,
+// case-insensitively. TTLs are compared in whole seconds as Dynu stores them.
+func DiffRecords(zone string, current, desired []libdns.Record) RecordDiff {
+	domain := zoneToFqdn(zone)
+	var diff RecordDiff
+
+	currentNormalized := make([]libdns.Record, len(current))
+	byID := make(map[string]int)
+	byKey := make(map[diffKey][]int)
+	for i, record := range current {
+		currentNormalized[i] = normalizeRecord(record, domain)
+		if record.ID != "" {
+			byID[record.ID] = i
+		}
+		key := newDiffKey(currentNormalized[i])
+		byKey[key] = append(byKey[key], i)
+	}
+	matched := make([]bool, len(current))
+
+	for _, record := range desired {
+		normalized := normalizeRecord(record, domain)
+
+		index := -1
+		if record.ID != "" {
+			if i, ok := byID[record.ID]; ok && !matched[i] {
+				index = i
+			}
+		} else {
+			for _, i := range byKey[newDiffKey(normalized)] {
+				if !matched[i] {
+					index = i
+					break
+				}
+			}
+		}
+
+		if index < 0 {
+			diff.Add = append(diff.Add, record)
+			continue
+		}
+
+		matched[index] = true
+		if fields := diffFields(currentNormalized[index], normalized); len(fields) > 0 {
+			diff.Change = append(diff.Change, RecordChange{Old: current[index], New: record, Fields: fields})
+		}
+	}
+
+	for i, record := range current {
+		if !matched[i] {
+			diff.Remove = append(diff.Remove, record)
+		}
+	}
+
+	return diff
+}
+
+type diffKey struct {
+	name       string
+	recordType string
+	value      string
+}
+
+func newDiffKey(record libdns.Record) diffKey {
+	return diffKey{record.Name, record.Type, record.Value}
+}
+
+// normalizeRecord brings a record into the form compared by DiffRecords
+func normalizeRecord(record libdns.Record, domain string) libdns.Record {
+	record.Type = strings.ToUpper(record.Type)
+	record.Name = normalizeName(record.Name, domain)
+	record.TTL = record.TTL.Truncate(time.Second)
+
+	if hasHostnameValue(record.Type) {
+		record.Value = strings.ToLower(strings.TrimSuffix(asciiName(record.Value), "."))
+	}
+	return record
+}
+
+func normalizeName(name, domain string) string {
+	name = strings.ToLower(asciiName(name))
+	domain = strings.ToLower(asciiName(domain))
+
+	switch {
+	case name == "" || name == "@" || name == domain+".":
+		return "@"
+	case strings.HasSuffix(name, "."+domain+"."):
+		return strings.TrimSuffix(name, "."+domain+".")
+	default:
+		return strings.TrimSuffix(name, ".")
+	}
+}
+
+func diffFields(old, new libdns.Record) []FieldChange {
+	var fields []FieldChange
+	add := func(field string, oldValue, newValue any) {
+		o, n := fmt.Sprint(oldValue), fmt.Sprint(newValue)
+		if o != n {
+			fields = append(fields, FieldChange{Field: field, Old: o, New: n})
+		}
+	}
+
+	add("Name", old.Name, new.Name)
+	add("Type", old.Type, new.Type)
+	add("Value", old.Value, new.Value)
+	add("TTL", old.TTL, new.TTL)
+	add("Priority", old.Priority, new.Priority)
+	return fields
+}
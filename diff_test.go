@@ -0,0 +1,60 @@
+package dynu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffRecords(t *testing.T) {
+	current := []libdns.Record{
+		{ID: "1", Type: "A", Name: "www", Value: "198.51.100.1", TTL: 300 * time.Second},
+		{ID: "2", Type: "CNAME", Name: "blog", Value: "example.github.io", TTL: 300 * time.Second},
+		{ID: "3", Type: "TXT", Name: "@", Value: "v=spf1 -all", TTL: 300 * time.Second},
+		{ID: "4", Type: "MX", Name: "@", Value: "mail.example.com", TTL: 3600 * time.Second, Priority: 10},
+		{ID: "5", Type: "TXT", Name: "old", Value: "remove me", TTL: 300 * time.Second},
+	}
+
+	desired := []libdns.Record{
+		// matched by ID
+		{ID: "1", Type: "A", Name: "www", Value: "203.0.113.1", TTL: 300 * time.Second},
+		// matched by name, type and value after normalisation
+		{Type: "cname", Name: "BLOG.example.com.", Value: "Example.GitHub.io.", TTL: 300*time.Second + 400*time.Millisecond},
+		{Type: "TXT", Name: "", Value: "v=spf1 -all", TTL: 300 * time.Second},
+		{Type: "MX", Name: "@", Value: "mail.example.com.", TTL: 600 * time.Second, Priority: 20},
+		// new
+		{Type: "TXT", Name: "new", Value: "added", TTL: 300 * time.Second},
+	}
+
+	diff := DiffRecords("example.com.", current, desired)
+
+	assert.Equal(t, []libdns.Record{desired[4]}, diff.Add)
+	assert.Equal(t, []libdns.Record{current[4]}, diff.Remove)
+
+	if assert.Len(t, diff.Change, 2) {
+		assert.Equal(t, current[0], diff.Change[0].Old)
+		assert.Equal(t, []FieldChange{{Field: "Value", Old: "198.51.100.1", New: "203.0.113.1"}}, diff.Change[0].Fields)
+
+		assert.Equal(t, current[3], diff.Change[1].Old)
+		assert.Equal(t, []FieldChange{
+			{Field: "TTL", Old: "1h0m0s", New: "10m0s"},
+			{Field: "Priority", Old: "10", New: "20"},
+		}, diff.Change[1].Fields)
+	}
+
+	assert.False(t, diff.Empty())
+	assert.True(t, DiffRecords("example.com.", current, current).Empty())
+}
+
+func TestDiffRecordsValueChangeWithoutID(t *testing.T) {
+	current := []libdns.Record{{ID: "1", Type: "A", Name: "www", Value: "198.51.100.1", TTL: time.Minute}}
+	desired := []libdns.Record{{Type: "A", Name: "www", Value: "203.0.113.1", TTL: time.Minute}}
+
+	diff := DiffRecords("example.com", current, desired)
+
+	assert.Equal(t, desired, diff.Add)
+	assert.Equal(t, current, diff.Remove)
+	assert.Empty(t, diff.Change)
+}
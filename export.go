@@ -0,0 +1,159 @@
+package dynu
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// maximum length of a DNS character-string
+const maxCharacterStringLength = 255
+
+// ExportOptions holds the zone metadata written by ExportZone.
+type ExportOptions struct {
+	// TTL is written as $TTL; records with this TTL omit theirs. If zero, the
+	// most common record TTL is used.
+	TTL time.Duration
+	// Comment is written as comment lines at the top of the file.
+	Comment string
+}
+
+// ExportZone writes records as returned by Provider.GetRecords as an RFC 1035
+// master file for zone. Names are kept relative to the zone and records are
+// sorted by name and type, so exports of an unchanged zone are identical.
+func ExportZone(w io.Writer, zone string, records []libdns.Record, options ExportOptions) error {
+	origin := zoneToFqdn(zone) + "."
+
+	ttl := options.TTL
+	if ttl == 0 {
+		ttl = mostCommonTTL(records)
+	}
+
+	sorted := append([]libdns.Record(nil), records...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Name != b.Name {
+			return a.Name == "@" || (b.Name != "@" && a.Name < b.Name)
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Value < b.Value
+	})
+
+	out := bufio.NewWriter(w)
+
+	if options.Comment != "" {
+		for _, line := range strings.Split(strings.TrimRight(options.Comment, "\n"), "\n") {
+			fmt.Fprintf(out, "; %s\n", line)
+		}
+	}
+	fmt.Fprintf(out, "$ORIGIN %s\n", origin)
+	fmt.Fprintf(out, "$TTL %d\n", int64(ttl.Seconds()))
+
+	for _, record := range sorted {
+		rdata, err := zoneFileRData(record)
+		if err != nil {
+			return err
+		}
+
+		owner := record.Name
+		if owner == "" {
+			owner = "@"
+		}
+		if record.Type == "PTR" && strings.HasSuffix(owner, ".arpa") {
+			// reverse names are absolute, see dnsRecordToLibdnsRecord
+			owner += "."
+		}
+
+		fmt.Fprint(out, owner)
+		if record.TTL != ttl && record.TTL > 0 {
+			fmt.Fprintf(out, "\t%d", int64(record.TTL.Seconds()))
+		}
+		fmt.Fprintf(out, "\tIN\t%s\t%s\n", record.Type, rdata)
+	}
+
+	return out.Flush()
+}
+
+func zoneFileRData(record libdns.Record) (string, error) {
+	switch record.Type {
+	case "A", "AAAA":
+		return record.Value, nil
+	case "CNAME", "NS", "PTR":
+		return absoluteTarget(record.Value), nil
+	case "MX":
+		return fmt.Sprintf("%d %s", record.Priority, absoluteTarget(record.Value)), nil
+	case "SPF", "TXT":
+		return quoteCharacterStrings(record.Value), nil
+	default:
+		if record.Value == "" {
+			return "", fmt.Errorf("dnsRecord %+v: no value to export", record)
+		}
+		return record.Value, nil
+	}
+}
+
+// Dynu returns target hostnames without the trailing dot
+func absoluteTarget(hostname string) string {
+	if hostname == "" || strings.HasSuffix(hostname, ".") {
+		return hostname
+	}
+	return hostname + "."
+}
+
+// quoteCharacterStrings quotes a TXT value, split into character-strings of
+// at most 255 bytes, escaping quotes, backslashes and non-printable bytes
+func quoteCharacterStrings(value string) string {
+	if value == "" {
+		return `""`
+	}
+
+	var parts []string
+	for len(value) > 0 {
+		n := len(value)
+		if n > maxCharacterStringLength {
+			n = maxCharacterStringLength
+		}
+		parts = append(parts, `"`+escapeCharacterString(value[:n])+`"`)
+		value = value[n:]
+	}
+	return strings.Join(parts, " ")
+}
+
+func escapeCharacterString(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			b.WriteString(`\` + fmt.Sprintf("%03d", c))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func mostCommonTTL(records []libdns.Record) time.Duration {
+	counts := make(map[time.Duration]int)
+	var best time.Duration
+	for _, record := range records {
+		counts[record.TTL]++
+		if counts[record.TTL] > counts[best] || (counts[record.TTL] == counts[best] && record.TTL < best) {
+			best = record.TTL
+		}
+	}
+	if best <= 0 {
+		return time.Hour
+	}
+	return best
+}
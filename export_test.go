@@ -0,0 +1,80 @@
+package dynu
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/stretchr/testify/assert"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+func assertGolden(t *testing.T, name string, actual []byte) {
+	path := filepath.Join("testdata", name)
+
+	if *updateGolden {
+		if err := os.WriteFile(path, actual, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := os.ReadFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, string(expected), string(actual))
+}
+
+func TestExportZone(t *testing.T) {
+	dnsRecords := []DNSRecord{
+		{ID: 1, Type: "TXT", NodeName: "", Hostname: "my.dynu.com", TextData: `v=spf1 include:"_spf.example.com" ~all`, TTL: 300},
+		{ID: 2, Type: "A", NodeName: "www", Hostname: "www.my.dynu.com", Ipv4Address: "203.0.113.1", TTL: 300},
+		{ID: 3, Type: "AAAA", NodeName: "www", Hostname: "www.my.dynu.com", Ipv6Address: "2001:db8::1", TTL: 300},
+		{ID: 4, Type: "MX", NodeName: "", Hostname: "my.dynu.com", Host: "mail.example.com", Priority: 10, TTL: 3600},
+		{ID: 5, Type: "CNAME", NodeName: "blog", Hostname: "blog.my.dynu.com", Host: "example.github.io", TTL: 300},
+		{ID: 6, Type: "NS", NodeName: "sub", Hostname: "sub.my.dynu.com", Host: "ns1.example.net", TTL: 86400},
+		{ID: 7, Type: "A", NodeName: "", Hostname: "my.dynu.com", Ipv4Address: "203.0.113.2", TTL: 300},
+		{ID: 8, Type: "TXT", NodeName: "dkim._domainkey", Hostname: "dkim._domainkey.my.dynu.com", TextData: "v=DKIM1; k=rsa; p=" + strings.Repeat("MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8A", 10), TTL: 300},
+		{ID: 9, Type: "TXT", NodeName: "tab", Hostname: "tab.my.dynu.com", TextData: "a\tb\\c", TTL: 300},
+	}
+
+	var records []libdns.Record
+	for _, dnsRecord := range dnsRecords {
+		records = append(records, dnsRecordToLibdnsRecord(dnsRecord, "my.dynu.com"))
+	}
+
+	var buffer bytes.Buffer
+	err := ExportZone(&buffer, "my.dynu.com.", records, ExportOptions{Comment: "Dynu zone my.dynu.com\nexported for backup"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assertGolden(t, filepath.Join("export", "my.dynu.com.zone"), buffer.Bytes())
+}
+
+func TestExportZoneTTL(t *testing.T) {
+	records := []libdns.Record{
+		{Type: "A", Name: "@", Value: "203.0.113.1", TTL: 60 * time.Second},
+		{Type: "A", Name: "www", Value: "203.0.113.1", TTL: 120 * time.Second},
+	}
+
+	var buffer bytes.Buffer
+	err := ExportZone(&buffer, "example.com", records, ExportOptions{TTL: time.Hour})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assertGolden(t, filepath.Join("export", "ttl.zone"), buffer.Bytes())
+}
+
+func TestQuoteCharacterStrings(t *testing.T) {
+	assert.Equal(t, `""`, quoteCharacterStrings(""))
+	assert.Equal(t, `"say \"hi\""`, quoteCharacterStrings(`say "hi"`))
+	assert.Equal(t, `"`+strings.Repeat("a", 255)+`" "b"`, quoteCharacterStrings(strings.Repeat("a", 255)+"b"))
+}
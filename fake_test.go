@@ -0,0 +1,233 @@
+package dynu
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDynu is an in-memory stand-in for the Dynu API; records are only kept
+// for the first domain
+type fakeDynu struct {
+	mutex   sync.Mutex
+	domain  DNSHostname
+	domains []Domain
+	records []DNSRecord
+	nextID  int64
+	// failures maps "METHOD path" to the number of requests to fail
+	failures map[string]int
+	// requests lists "METHOD path" of all requests received
+	requests []string
+}
+
+func newFakeDynu(domainName string, records ...DNSRecord) *fakeDynu {
+	fake := &fakeDynu{
+		domain:   DNSHostname{StatusCode: 200, ID: 100, DomainName: domainName, Hostname: domainName},
+		domains:  []Domain{{ID: 100, Name: domainName, State: "Complete", TTL: 90, Ipv4: true, Ipv6: true}},
+		nextID:   1000,
+		failures: make(map[string]int),
+	}
+	for _, record := range records {
+		fake.records = append(fake.records, fake.complete(record))
+	}
+	return fake
+}
+
+// newFakeProvider returns a provider using a fakeDynu for the zone
+func newFakeProvider(t *testing.T, zone string, records ...DNSRecord) (*Provider, *fakeDynu) {
+	fake := newFakeDynu(zoneToFqdn(zone), records...)
+	provider := &Provider{APIToken: "token", OwnDomain: zoneToFqdn(zone)}
+	provider.Client = newTestClient(t, fake)
+	return provider, fake
+}
+
+func (f *fakeDynu) complete(record DNSRecord) DNSRecord {
+	if record.ID == 0 {
+		record.ID = f.nextID
+		f.nextID++
+	}
+	record.DomainID = f.domain.ID
+	record.DomainName = f.domain.DomainName
+	record.Hostname = f.domain.DomainName
+	if record.NodeName != "" {
+		record.Hostname = record.NodeName + "." + f.domain.DomainName
+	}
+	record.StatusCode = 0
+	return record
+}
+
+func (f *fakeDynu) fail(method, path string, times int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.failures[method+" "+path] = times
+}
+
+func (f *fakeDynu) Records() []DNSRecord {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]DNSRecord(nil), f.records...)
+}
+
+func (f *fakeDynu) Requests() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.requests...)
+}
+
+func (f *fakeDynu) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(APIException{StatusCode: int32(statusCode), Type: http.StatusText(statusCode), Message: message})
+}
+
+func (f *fakeDynu) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	key := r.Method + " " + r.URL.Path
+	f.requests = append(f.requests, key)
+	if f.failures[key] > 0 {
+		f.failures[key]--
+		f.writeError(w, http.StatusBadRequest, "injected failure")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	domainID := strconv.FormatInt(f.domain.ID, 10)
+
+	switch {
+	case len(parts) == 1 && parts[0] == "dns" && r.Method == http.MethodGet:
+		_ = json.NewEncoder(w).Encode(DomainsResponse{StatusCode: 200, Domains: f.domains})
+	case len(parts) == 1 && parts[0] == "dns" && r.Method == http.MethodPost:
+		var request DomainRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			f.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		domain := Domain{ID: f.nextID, State: "Complete"}
+		f.nextID++
+		f.applyDomainRequest(&domain, request)
+		f.domains = append(f.domains, domain)
+		domain.StatusCode = 200
+		_ = json.NewEncoder(w).Encode(domain)
+	case len(parts) == 2 && parts[0] == "dns" && parts[1] != "getroot":
+		id, _ := strconv.ParseInt(parts[1], 10, 64)
+		index := -1
+		for i := range f.domains {
+			if f.domains[i].ID == id {
+				index = i
+			}
+		}
+		if index < 0 {
+			f.writeError(w, http.StatusNotFound, fmt.Sprintf("domain %d not found", id))
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			domain := f.domains[index]
+			domain.StatusCode = 200
+			_ = json.NewEncoder(w).Encode(domain)
+		case http.MethodPost:
+			var request DomainRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				f.writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			f.applyDomainRequest(&f.domains[index], request)
+			_ = json.NewEncoder(w).Encode(UpdateResponse{StatusCode: 200})
+		case http.MethodDelete:
+			f.domains = append(f.domains[:index], f.domains[index+1:]...)
+			_ = json.NewEncoder(w).Encode(DeleteResponse{StatusCode: 200})
+		}
+	case len(parts) == 3 && parts[0] == "dns" && parts[1] == "record" && r.Method == http.MethodGet:
+		records := []DNSRecord{}
+		for _, record := range f.records {
+			if record.Hostname == parts[2] && (r.URL.Query().Get("recordType") == "" || record.Type == r.URL.Query().Get("recordType")) {
+				records = append(records, record)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(RecordsResponse{StatusCode: 200, DNSRecords: records})
+	case len(parts) == 3 && parts[0] == "dns" && parts[1] == "getroot":
+		hostname := strings.ToLower(parts[2])
+		root := f.domain
+		switch {
+		case hostname == root.DomainName:
+		case strings.HasSuffix(hostname, "."+root.DomainName):
+			root.Node = strings.TrimSuffix(hostname, "."+root.DomainName)
+			root.Hostname = hostname
+		default:
+			f.writeError(w, http.StatusNotFound, fmt.Sprintf("%s not found", hostname))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(root)
+	case len(parts) == 3 && parts[0] == "dns" && parts[1] == domainID && parts[2] == "record" && r.Method == http.MethodGet:
+		_ = json.NewEncoder(w).Encode(RecordsResponse{StatusCode: 200, DNSRecords: f.records})
+	case len(parts) >= 3 && parts[0] == "dns" && parts[1] == domainID && parts[2] == "record" && r.Method == http.MethodPost:
+		var record DNSRecord
+		if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+			f.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		record.ID = 0
+		if len(parts) == 4 {
+			record.ID, _ = strconv.ParseInt(parts[3], 10, 64)
+		}
+		f.upsert(w, record)
+	case len(parts) == 4 && parts[0] == "dns" && parts[1] == domainID && parts[2] == "record" && r.Method == http.MethodDelete:
+		id, _ := strconv.ParseInt(parts[3], 10, 64)
+		for i, record := range f.records {
+			if record.ID == id {
+				f.records = append(f.records[:i], f.records[i+1:]...)
+				_ = json.NewEncoder(w).Encode(DeleteResponse{StatusCode: 200})
+				return
+			}
+		}
+		f.writeError(w, http.StatusNotFound, fmt.Sprintf("record %d not found", id))
+	default:
+		f.writeError(w, http.StatusNotFound, "unknown endpoint "+key)
+	}
+}
+
+func (f *fakeDynu) applyDomainRequest(domain *Domain, request DomainRequest) {
+	domain.Name = request.Name
+	domain.Group = request.Group
+	domain.Ipv4Address = request.Ipv4Address
+	domain.Ipv6Address = request.Ipv6Address
+	domain.TTL = request.TTL
+	domain.Ipv4 = request.Ipv4
+	domain.Ipv6 = request.Ipv6
+	domain.Ipv4WildcardAlias = request.Ipv4WildcardAlias
+	domain.Ipv6WildcardAlias = request.Ipv6WildcardAlias
+	domain.AllowZoneTransfer = request.AllowZoneTransfer
+	domain.Dnssec = request.Dnssec
+}
+
+func (f *fakeDynu) Domains() []Domain {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]Domain(nil), f.domains...)
+}
+
+func (f *fakeDynu) upsert(w http.ResponseWriter, record DNSRecord) {
+	if record.ID != 0 {
+		for i := range f.records {
+			if f.records[i].ID == record.ID {
+				f.records[i] = f.complete(record)
+				response := f.records[i]
+				response.StatusCode = 200
+				_ = json.NewEncoder(w).Encode(response)
+				return
+			}
+		}
+		f.writeError(w, http.StatusNotFound, fmt.Sprintf("record %d not found", record.ID))
+		return
+	}
+
+	record = f.complete(record)
+	f.records = append(f.records, record)
+	record.StatusCode = 200
+	_ = json.NewEncoder(w).Encode(record)
+}
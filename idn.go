@@ -0,0 +1,64 @@
+package dynu
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// idnaProfile converts names by IDNA2008 as for lookups, but allows the
+// underscores of service names and the * of wildcards
+var idnaProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.Transitional(false),
+	idna.StrictDomainName(false),
+)
+
+// toASCII returns the punycode form of an internationalised domain name.
+// ASCII names are returned unchanged.
+func toASCII(name string) (string, error) {
+	if isASCII(name) {
+		return name, nil
+	}
+	return idnaProfile.ToASCII(name)
+}
+
+// asciiName is toASCII for conversions that cannot fail; invalid names are
+// returned unchanged for ValidateRecords or Dynu to refuse
+func asciiName(name string) string {
+	if ascii, err := toASCII(name); err == nil {
+		return ascii
+	}
+	return name
+}
+
+// unicodeName returns the Unicode form of a name with punycode labels
+func unicodeName(name string) string {
+	if !strings.Contains(name, "xn--") && !strings.Contains(name, "XN--") {
+		return name
+	}
+	if unicode, err := idnaProfile.ToUnicode(name); err == nil {
+		return unicode
+	}
+	return name
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// hasHostnameValue reports whether the value of a record type is a hostname
+func hasHostnameValue(recordType string) bool {
+	switch recordType {
+	case "CNAME", "MX", "NS", "PTR":
+		return true
+	}
+	return false
+}
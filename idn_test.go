@@ -0,0 +1,104 @@
+package dynu
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToASCII(t *testing.T) {
+	tests := map[string]string{
+		"www":                            "www",
+		"WWW.Example.com":                "WWW.Example.com",
+		"bücher.example":                 "xn--bcher-kva.example",
+		"BÜCHER.example.":                "xn--bcher-kva.example.",
+		"_acme-challenge.bücher.example": "_acme-challenge.xn--bcher-kva.example",
+		"*.bücher.example":               "*.xn--bcher-kva.example",
+		"ｅｘａｍｐｌｅ.bücher":                 "example.xn--bcher-kva",
+	}
+	for name, expected := range tests {
+		ascii, err := toASCII(name)
+		if assert.NoError(t, err, name) {
+			assert.Equal(t, expected, ascii, name)
+		}
+	}
+
+	_, err := toASCII("a‍.bücher")
+	assert.Error(t, err, "joiners are not allowed by IDNA2008")
+
+	assert.Equal(t, "bücher.example", unicodeName("xn--bcher-kva.example"))
+	assert.Equal(t, "xn--zz.example", unicodeName("xn--zz.example"))
+}
+
+func TestIDNRecords(t *testing.T) {
+	provider, fake := newFakeProvider(t, "xn--bcher-kva.example.")
+
+	_, err := provider.AppendRecords(context.TODO(), "bücher.example.", []libdns.Record{
+		{Type: "A", Name: "straße", Value: "203.0.113.1", TTL: 5 * time.Minute},
+		{Type: "CNAME", Name: "www", Value: "bücher.example.", TTL: 5 * time.Minute},
+		{Type: "MX", Name: "@", Value: "mail.bücher.example", Priority: 10, TTL: 5 * time.Minute},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	records := fake.Records()
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, "xn--strae-oqa", records[0].NodeName, "IDNA2008 keeps ß")
+		assert.Equal(t, "xn--bcher-kva.example.", records[1].Host)
+		assert.Equal(t, "mail.xn--bcher-kva.example", records[2].Host)
+	}
+	assert.Contains(t, fake.Requests(), "GET /dns/getroot/xn--bcher-kva.example")
+
+	read, err := provider.GetRecords(context.TODO(), "bücher.example.")
+	if assert.NoError(t, err) && assert.Len(t, read, 3) {
+		assert.Equal(t, "xn--bcher-kva.example.", read[1].Value, "punycode by default")
+	}
+
+	provider.UnicodeNames = true
+	read, err = provider.GetRecords(context.TODO(), "bücher.example.")
+	if assert.NoError(t, err) && assert.Len(t, read, 3) {
+		assert.Equal(t, "www", read[1].Name)
+		assert.Equal(t, "bücher.example.", read[1].Value)
+		assert.Equal(t, "mail.bücher.example", read[2].Value)
+	}
+
+	queried, err := provider.QueryRecords(context.TODO(), "bücher.example.", RecordQuery{NameSuffix: "Straße"})
+	if assert.NoError(t, err) {
+		assert.Len(t, queried, 1)
+	}
+}
+
+func TestIDNUnicodeRecordName(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.")
+	provider.UnicodeNames = true
+
+	created, err := provider.AppendRecords(context.TODO(), "example.com.", []libdns.Record{
+		{Type: "TXT", Name: "bücher", Value: "hello", TTL: 5 * time.Minute},
+	})
+	if assert.NoError(t, err) && assert.Len(t, created, 1) {
+		assert.Equal(t, "bücher", created[0].Name)
+	}
+	assert.Equal(t, "xn--bcher-kva", fake.Records()[0].NodeName)
+
+	// names are compared in punycode
+	_, err = provider.AppendRecords(context.TODO(), "example.com.", []libdns.Record{
+		{Type: "CNAME", Name: "xn--bcher-kva", Value: "example.net"},
+	})
+	assert.ErrorContains(t, err, "CNAME must be the only record at its name, found TXT")
+}
+
+func TestIDNValidation(t *testing.T) {
+	err := ValidateRecords("example.com.", []libdns.Record{
+		{Type: "CNAME", Name: "a‍", Value: "bücher.example"},
+		{Type: "CNAME", Name: "ok", Value: "b‍ücher.example"},
+	}, nil)
+	if assert.Error(t, err) {
+		assert.Equal(t, 2, len(err.(*ValidationError).Violations))
+		assert.Contains(t, err.Error(), "invalid name: idna:")
+		assert.Contains(t, err.Error(), "invalid target: idna:")
+	}
+}
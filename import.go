@@ -0,0 +1,286 @@
+package dynu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/miekg/dns"
+)
+
+// ZoneFileEntry identifies a resource record in a zone file.
+type ZoneFileEntry struct {
+	// Index is the 1-based position of the record in the zone file.
+	Index int
+	Name  string
+	Type  string
+}
+
+func (e ZoneFileEntry) String() string {
+	return fmt.Sprintf("record %d (%s %s)", e.Index, e.Name, e.Type)
+}
+
+// ParsedZone is the content of a zone file converted to libdns records.
+type ParsedZone struct {
+	Records []libdns.Record
+	// Unsupported lists records of types Dynu records cannot hold.
+	Unsupported []ZoneFileEntry
+	// Ignored lists records managed by Dynu itself, i.e. SOA.
+	Ignored []ZoneFileEntry
+}
+
+// ParseZoneFile parses an RFC 1035 master file for zone into records named
+// like Provider.GetRecords names them.
+func ParseZoneFile(r io.Reader, zone string) (*ParsedZone, error) {
+	origin := dns.Fqdn(zoneToFqdn(zone))
+	parser := dns.NewZoneParser(r, origin, "")
+	parsed := &ParsedZone{}
+
+	index := 0
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		index++
+		header := rr.Header()
+		entry := ZoneFileEntry{Index: index, Name: header.Name, Type: dns.TypeToString[header.Rrtype]}
+
+		if header.Rrtype == dns.TypeSOA {
+			parsed.Ignored = append(parsed.Ignored, entry)
+			continue
+		}
+
+		record, ok := rrToLibdnsRecord(rr, origin)
+		if !ok {
+			parsed.Unsupported = append(parsed.Unsupported, entry)
+			continue
+		}
+		parsed.Records = append(parsed.Records, record)
+	}
+
+	if err := parser.Err(); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+func rrToLibdnsRecord(rr dns.RR, origin string) (libdns.Record, bool) {
+	header := rr.Header()
+
+	name := libdns.RelativeName(header.Name, origin)
+	if name == "" {
+		name = "@"
+	}
+
+	record := libdns.Record{
+		Type: dns.TypeToString[header.Rrtype],
+		Name: name,
+		TTL:  time.Duration(header.Ttl) * time.Second,
+	}
+
+	// hostnames without trailing dot as returned by Dynu
+	switch rr := rr.(type) {
+	case *dns.A:
+		record.Value = rr.A.String()
+	case *dns.AAAA:
+		record.Value = rr.AAAA.String()
+	case *dns.CNAME:
+		record.Value = strings.TrimSuffix(rr.Target, ".")
+	case *dns.MX:
+		record.Value = strings.TrimSuffix(rr.Mx, ".")
+		record.Priority = uint(rr.Preference)
+	case *dns.NS:
+		record.Value = strings.TrimSuffix(rr.Ns, ".")
+	case *dns.PTR:
+		if !isReverseZone(origin) {
+			// reverse names are absolute outside of reverse zones
+			record.Name = strings.TrimSuffix(header.Name, ".")
+		}
+		record.Value = strings.TrimSuffix(rr.Ptr, ".")
+	case *dns.SPF:
+		record.Value = joinCharacterStrings(rr.Txt)
+	case *dns.TXT:
+		record.Value = joinCharacterStrings(rr.Txt)
+	default:
+		return record, false
+	}
+
+	return record, true
+}
+
+// joinCharacterStrings concatenates TXT character-strings, which miekg/dns
+// keeps in escaped presentation format. They are unescaped as a whole, as
+// miekg/dns may split long strings in the middle of an escape.
+func joinCharacterStrings(parts []string) string {
+	return unescapeCharacterString(strings.Join(parts, ""))
+}
+
+// unescapeCharacterString resolves \X and \DDD escapes
+func unescapeCharacterString(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+3 < len(s) && isDigit(s[i+1]) && isDigit(s[i+2]) && isDigit(s[i+3]) {
+			code := int(s[i+1]-'0')*100 + int(s[i+2]-'0')*10 + int(s[i+3]-'0')
+			if code <= 255 {
+				b.WriteByte(byte(code))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i+1])
+		i++
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// ImportOptions controls Provider.ImportZone.
+type ImportOptions struct {
+	// ReplaceRRsets replaces the existing records of every name and type in
+	// the zone file, instead of appending the records.
+	ReplaceRRsets bool
+	// SkipUnsupported imports the supported records of a zone file that
+	// contains unsupported ones instead of failing.
+	SkipUnsupported bool
+	// Preview plans the changes without making them.
+	Preview bool
+}
+
+// ImportPlan lists the changes of an import.
+type ImportPlan struct {
+	Create []libdns.Record
+	Update []libdns.Record
+	Delete []libdns.Record
+	// Unsupported and Ignored are taken from ParsedZone.
+	Unsupported []ZoneFileEntry
+	Ignored     []ZoneFileEntry
+}
+
+// ImportZone parses a zone file and adds its records to zone. All records
+// are checked before any change is made. With ReplaceRRsets, existing
+// records are updated in place where possible, which keeps their IDs.
+func (p *Provider) ImportZone(ctx context.Context, zone string, r io.Reader, options ImportOptions) (*ImportPlan, error) {
+	p.Once.Do(func() { p.init() })
+
+	parsed, err := ParseZoneFile(r, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &ImportPlan{Unsupported: parsed.Unsupported, Ignored: parsed.Ignored}
+
+	if len(parsed.Unsupported) > 0 && !options.SkipUnsupported {
+		var errs []error
+		for _, entry := range parsed.Unsupported {
+			errs = append(errs, fmt.Errorf("%s: record type not supported", entry))
+		}
+		return plan, errors.Join(errs...)
+	}
+
+	// the records among themselves; AppendRecords and SetRecords check
+	// them against the zone again
+	if err := ValidateRecords(zone, parsed.Records, nil); err != nil {
+		return plan, err
+	}
+
+	var errs []error
+
+	if !options.ReplaceRRsets {
+		plan.Create = parsed.Records
+	} else {
+		existing, err := p.GetRecords(ctx, zone)
+		if err != nil {
+			return plan, err
+		}
+		plan.Create, plan.Update, plan.Delete = planRRsets(existing, parsed.Records)
+	}
+
+	if options.Preview {
+		return plan, nil
+	}
+
+	if len(plan.Update) > 0 {
+		if _, err := p.SetRecords(ctx, zone, plan.Update); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(plan.Create) > 0 {
+		if _, err := p.AppendRecords(ctx, zone, plan.Create); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(plan.Delete) > 0 {
+		if _, err := p.DeleteRecords(ctx, zone, plan.Delete); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return plan, errors.Join(errs...)
+}
+
+type rrsetKey struct {
+	name       string
+	recordType string
+}
+
+// planRRsets replaces the existing records of every name and type in
+// desired, reusing existing records (and IDs) for the new values
+func planRRsets(existing, desired []libdns.Record) (create, update, remove []libdns.Record) {
+	current := make(map[rrsetKey][]libdns.Record)
+	for _, record := range existing {
+		key := rrsetKey{record.Name, record.Type}
+		current[key] = append(current[key], record)
+	}
+
+	wanted := make(map[rrsetKey][]libdns.Record)
+	var keys []rrsetKey
+	for _, record := range desired {
+		key := rrsetKey{record.Name, record.Type}
+		if _, ok := wanted[key]; !ok {
+			keys = append(keys, key)
+		}
+		wanted[key] = append(wanted[key], record)
+	}
+
+	for _, key := range keys {
+		var unmatched []libdns.Record
+		available := current[key]
+
+		// keep records that are already as wanted
+		for _, record := range wanted[key] {
+			index := -1
+			for i, candidate := range available {
+				if candidate.Value == record.Value && candidate.TTL == record.TTL && candidate.Priority == record.Priority {
+					index = i
+					break
+				}
+			}
+			if index >= 0 {
+				available = append(available[:index:index], available[index+1:]...)
+			} else {
+				unmatched = append(unmatched, record)
+			}
+		}
+
+		for _, record := range unmatched {
+			if len(available) > 0 {
+				record.ID = available[0].ID
+				available = available[1:]
+				update = append(update, record)
+			} else {
+				create = append(create, record)
+			}
+		}
+		remove = append(remove, available...)
+	}
+
+	return create, update, remove
+}
@@ -0,0 +1,113 @@
+package dynu
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseZoneFile(t *testing.T) {
+	file, err := os.Open(filepath.Join("testdata", "import.zone"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer file.Close()
+
+	parsed, err := ParseZoneFile(file, "example.com.")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []libdns.Record{
+		{Type: "A", Name: "@", Value: "203.0.113.1", TTL: 300 * time.Second},
+		{Type: "MX", Name: "@", Value: "mail.example.com", TTL: time.Hour, Priority: 10},
+		{Type: "TXT", Name: "@", Value: "v=spf1 -all", TTL: 300 * time.Second},
+		{Type: "CNAME", Name: "www", Value: "example.com", TTL: 300 * time.Second},
+		{Type: "AAAA", Name: "api", Value: "2001:db8::1", TTL: 300 * time.Second},
+	}, parsed.Records)
+	assert.Equal(t, []ZoneFileEntry{{Index: 7, Name: "_sip._tcp.example.com.", Type: "SRV"}}, parsed.Unsupported)
+	assert.Equal(t, []ZoneFileEntry{{Index: 1, Name: "example.com.", Type: "SOA"}}, parsed.Ignored)
+}
+
+func TestParseZoneFileRoundTrip(t *testing.T) {
+	golden, err := os.ReadFile(filepath.Join("testdata", "export", "my.dynu.com.zone"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	parsed, err := ParseZoneFile(bytes.NewReader(golden), "my.dynu.com.")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Empty(t, parsed.Unsupported)
+
+	var exported bytes.Buffer
+	err = ExportZone(&exported, "my.dynu.com.", parsed.Records, ExportOptions{Comment: "Dynu zone my.dynu.com\nexported for backup"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(golden), exported.String())
+	}
+}
+
+func TestImportZoneReportsUnsupported(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.")
+
+	file, err := os.ReadFile(filepath.Join("testdata", "import.zone"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	plan, err := provider.ImportZone(context.TODO(), "example.com.", bytes.NewReader(file), ImportOptions{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "_sip._tcp.example.com. SRV")
+	}
+	assert.Len(t, plan.Unsupported, 1)
+	assert.Empty(t, fake.Records(), "no change must be made")
+}
+
+func TestImportZoneReplaceRRsets(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.",
+		DNSRecord{ID: 1, Type: "A", Ipv4Address: "198.51.100.1", TTL: 300, State: true},
+		DNSRecord{ID: 2, Type: "A", Ipv4Address: "198.51.100.2", TTL: 300, State: true},
+		DNSRecord{ID: 3, Type: "CNAME", NodeName: "www", Host: "example.com", TTL: 300, State: true},
+		DNSRecord{ID: 4, Type: "TXT", NodeName: "keep", TextData: "untouched", TTL: 300, State: true},
+	)
+
+	zoneFile := strings.Join([]string{
+		"$TTL 300",
+		"@ IN A 203.0.113.1",
+		"www IN CNAME example.com.",
+		"api IN AAAA 2001:db8::1",
+	}, "\n")
+
+	plan, err := provider.ImportZone(context.TODO(), "example.com.", strings.NewReader(zoneFile), ImportOptions{ReplaceRRsets: true, Preview: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, plan.Update, 1)
+	assert.Equal(t, "1", plan.Update[0].ID)
+	assert.Len(t, plan.Create, 1)
+	assert.Equal(t, "api", plan.Create[0].Name)
+	assert.Len(t, plan.Delete, 1)
+	assert.Equal(t, "2", plan.Delete[0].ID)
+	assert.Len(t, fake.Records(), 4, "preview must not make changes")
+
+	_, err = provider.ImportZone(context.TODO(), "example.com.", strings.NewReader(zoneFile), ImportOptions{ReplaceRRsets: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	records := fake.Records()
+	if assert.Len(t, records, 4) {
+		assert.Equal(t, "203.0.113.1", records[0].Ipv4Address)
+		assert.Equal(t, int64(1), records[0].ID)
+		assert.Equal(t, "untouched", records[2].TextData)
+		assert.Equal(t, "2001:db8::1", records[3].Ipv6Address)
+	}
+}
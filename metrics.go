@@ -0,0 +1,30 @@
+package dynu
+
+import "time"
+
+// Outcome classifies the result of a single request to the Dynu API.
+type Outcome string
+
+const (
+	// OutcomeSuccess is a request answered with status code 200.
+	OutcomeSuccess Outcome = "success"
+	// OutcomeAPIError is a request answered by Dynu with an error status code.
+	OutcomeAPIError Outcome = "api_error"
+	// OutcomeError is a request that failed before a Dynu response could be decoded.
+	OutcomeError Outcome = "error"
+)
+
+// Metrics receives observations about the API usage of a Client.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// ObserveRequest is called once per HTTP attempt with the name of the
+	// Client method that made it (e.g. "GetRecords").
+	ObserveRequest(endpoint string, outcome Outcome, duration time.Duration)
+	// ObserveRetry is called each time a failed request is retried.
+	ObserveRetry(endpoint string)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveRequest(string, Outcome, time.Duration) {}
+func (nopMetrics) ObserveRetry(string)                           {}
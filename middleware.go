@@ -0,0 +1,34 @@
+package dynu
+
+import (
+	"context"
+	"net/http"
+)
+
+// Call is a single request to the Dynu API as seen by a Middleware.
+type Call struct {
+	// Endpoint is the name of the Client method making the call, e.g. "GetRecords".
+	Endpoint string
+	Method   string
+	URL      string
+	// Header and Body may be modified before passing the call on.
+	Header http.Header
+	Body   []byte
+
+	// StatusCode is the HTTP status code, set once a response was received.
+	StatusCode int
+	// Result is the decoded Dynu response, e.g. *RecordsResponse.
+	Result any
+	// Exception holds the error fields of the response when Dynu reports a failure.
+	Exception *APIException
+}
+
+// Handler performs a Call, filling in its response fields.
+type Handler func(ctx context.Context, call *Call) error
+
+// Middleware wraps a Handler to inspect or modify calls and their results.
+// A middleware may answer a call itself without calling next, e.g. to serve
+// a cached response or inject a fault. An error returned without setting
+// StatusCode is treated as a network error and retried if Client.MaxRetries
+// allows, except for requests creating a record or domain.
+type Middleware func(next Handler) Handler
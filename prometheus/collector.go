@@ -0,0 +1,76 @@
+// Package prometheus provides a dynu.Metrics implementation that exports
+// Dynu API usage as Prometheus metrics.
+package prometheus
+
+import (
+	"time"
+
+	dynu "github.com/taviowong/libdns-dynu"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector records dynu.Client observations. Register it with a
+// prometheus.Registerer and assign it to Client.Metrics.
+type Collector struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	retries  *prometheus.CounterVec
+}
+
+// NewCollector creates a Collector whose metrics are prefixed with namespace,
+// e.g. "caddy" gives caddy_dynu_requests_total.
+func NewCollector(namespace string) *Collector {
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "dynu",
+			Name:      "requests_total",
+			Help:      "Number of Dynu API requests by endpoint and outcome.",
+		}, []string{"endpoint", "outcome"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "dynu",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of Dynu API requests by endpoint.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"endpoint"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "dynu",
+			Name:      "retries_total",
+			Help:      "Number of retried Dynu API requests by endpoint.",
+		}, []string{"endpoint"}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.duration.Describe(ch)
+	c.retries.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.duration.Collect(ch)
+	c.retries.Collect(ch)
+}
+
+// ObserveRequest implements dynu.Metrics.
+func (c *Collector) ObserveRequest(endpoint string, outcome dynu.Outcome, duration time.Duration) {
+	c.requests.WithLabelValues(endpoint, string(outcome)).Inc()
+	c.duration.WithLabelValues(endpoint).Observe(duration.Seconds())
+}
+
+// ObserveRetry implements dynu.Metrics.
+func (c *Collector) ObserveRetry(endpoint string) {
+	c.retries.WithLabelValues(endpoint).Inc()
+}
+
+// Interface guards
+var (
+	_ dynu.Metrics         = (*Collector)(nil)
+	_ prometheus.Collector = (*Collector)(nil)
+)
@@ -0,0 +1,34 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	dynu "github.com/taviowong/libdns-dynu"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	collector := NewCollector("test")
+	registry := prometheus.NewRegistry()
+	if !assert.NoError(t, registry.Register(collector)) {
+		return
+	}
+
+	collector.ObserveRequest("GetRecords", dynu.OutcomeSuccess, 100*time.Millisecond)
+	collector.ObserveRequest("GetRecords", dynu.OutcomeSuccess, 200*time.Millisecond)
+	collector.ObserveRequest("DeleteRecord", dynu.OutcomeAPIError, 50*time.Millisecond)
+	collector.ObserveRetry("DeleteRecord")
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(collector.requests.WithLabelValues("GetRecords", "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.requests.WithLabelValues("DeleteRecord", "api_error")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.retries.WithLabelValues("DeleteRecord")))
+
+	count, err := testutil.GatherAndCount(registry, "test_dynu_request_duration_seconds")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, count)
+	}
+}
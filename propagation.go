@@ -0,0 +1,288 @@
+package dynu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/miekg/dns"
+)
+
+// Resolver sends a DNS query to a server ("host:port").
+type Resolver interface {
+	Exchange(ctx context.Context, msg *dns.Msg, server string) (*dns.Msg, error)
+}
+
+// dnsResolver queries over UDP and retries over TCP on truncated responses
+type dnsResolver struct{}
+
+func (dnsResolver) Exchange(ctx context.Context, msg *dns.Msg, server string) (*dns.Msg, error) {
+	response, _, err := (&dns.Client{Net: "udp"}).ExchangeContext(ctx, msg, server)
+	if err == nil && response.Truncated {
+		response, _, err = (&dns.Client{Net: "tcp"}).ExchangeContext(ctx, msg, server)
+	}
+	return response, err
+}
+
+const (
+	defaultPropagationTimeout  = 2 * time.Minute
+	defaultPropagationInterval = 5 * time.Second
+	defaultRecursiveServer     = "1.1.1.1:53"
+)
+
+// PropagationChecker waits until records are served by all authoritative
+// nameservers of a zone, e.g. before asking an ACME CA to validate a
+// DNS-01 challenge.
+type PropagationChecker struct {
+	// Resolver sends the queries; plain DNS over UDP/TCP by default.
+	Resolver Resolver
+	// RecursiveServer is used to look up the nameservers of the zone; the
+	// first server of /etc/resolv.conf or 1.1.1.1:53 by default.
+	RecursiveServer string
+	// Nameservers ("host:port") skips the lookup of the zone's nameservers.
+	Nameservers []string
+	// Timeout of Wait; two minutes by default.
+	Timeout time.Duration
+	// Interval between checks; five seconds by default.
+	Interval time.Duration
+}
+
+// nameserver is an authoritative nameserver with its addresses ("host:port")
+type nameserver struct {
+	name      string
+	addresses []string
+}
+
+// Wait blocks until the record is visible on all authoritative nameservers
+// of the zone, the timeout elapses or ctx is done. A nameserver with several
+// addresses counts as reached once any of them serves the record, so
+// addresses of an unreachable family, e.g. IPv6 on an IPv4-only host, do
+// not hold it up.
+func (c *PropagationChecker) Wait(ctx context.Context, zone string, record libdns.Record) error {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultPropagationTimeout
+	}
+	interval := c.Interval
+	if interval <= 0 {
+		interval = defaultPropagationInterval
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	nameservers, err := c.nameservers(ctx, zone)
+	if err != nil {
+		return fmt.Errorf("find nameservers of %s: %w", zone, err)
+	}
+
+	pending := nameservers
+	for {
+		var stillPending []nameserver
+		var lastErr error
+		for _, ns := range pending {
+			visible := false
+			for _, server := range ns.addresses {
+				var err error
+				if visible, err = c.visible(ctx, server, zone, record); err != nil {
+					lastErr = err
+				}
+				if visible {
+					break
+				}
+			}
+			if !visible {
+				stillPending = append(stillPending, ns)
+			}
+		}
+
+		pending = stillPending
+		if len(pending) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			names := make([]string, len(pending))
+			for i, ns := range pending {
+				names[i] = ns.name
+			}
+			err := fmt.Errorf("record %s %s not visible on %s after %s", record.Name, record.Type, strings.Join(names, ", "), timeout)
+			return errors.Join(err, lastErr)
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (c *PropagationChecker) resolver() Resolver {
+	if c.Resolver == nil {
+		return dnsResolver{}
+	}
+	return c.Resolver
+}
+
+func (c *PropagationChecker) recursiveServer() string {
+	if c.RecursiveServer != "" {
+		return c.RecursiveServer
+	}
+	config, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	if err != nil || len(config.Servers) == 0 {
+		return defaultRecursiveServer
+	}
+	return net.JoinHostPort(config.Servers[0], config.Port)
+}
+
+// nameservers returns the authoritative nameservers with their addresses
+func (c *PropagationChecker) nameservers(ctx context.Context, zone string) ([]nameserver, error) {
+	if len(c.Nameservers) > 0 {
+		nameservers := make([]nameserver, len(c.Nameservers))
+		for i, server := range c.Nameservers {
+			nameservers[i] = nameserver{name: server, addresses: []string{server}}
+		}
+		return nameservers, nil
+	}
+
+	recursive := c.recursiveServer()
+
+	names, err := c.zoneCut(ctx, recursive, dns.Fqdn(zoneToFqdn(zone)))
+	if err != nil {
+		return nil, err
+	}
+
+	var nameservers []nameserver
+	for _, name := range names {
+		ns := nameserver{name: strings.TrimSuffix(name, ".")}
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			hostAnswers, err := c.query(ctx, recursive, name, qtype)
+			if err != nil {
+				return nil, err
+			}
+			for _, hostAnswer := range hostAnswers {
+				switch rr := hostAnswer.(type) {
+				case *dns.A:
+					ns.addresses = append(ns.addresses, net.JoinHostPort(rr.A.String(), "53"))
+				case *dns.AAAA:
+					ns.addresses = append(ns.addresses, net.JoinHostPort(rr.AAAA.String(), "53"))
+				}
+			}
+		}
+		if len(ns.addresses) > 0 {
+			nameservers = append(nameservers, ns)
+		}
+	}
+
+	if len(nameservers) == 0 {
+		return nil, errors.New("no nameservers found")
+	}
+	sort.Slice(nameservers, func(i, j int) bool { return nameservers[i].name < nameservers[j].name })
+	return nameservers, nil
+}
+
+// zoneCut returns the NS names of the zone holding name, walking up the
+// labels to the closest name with NS records, e.g. from a Dynu hostname
+// without a delegation of its own to its domain
+func (c *PropagationChecker) zoneCut(ctx context.Context, recursive, name string) ([]string, error) {
+	for {
+		answers, err := c.query(ctx, recursive, name, dns.TypeNS)
+		if err != nil {
+			return nil, err
+		}
+
+		var names []string
+		for _, answer := range answers {
+			if ns, ok := answer.(*dns.NS); ok && dns.CanonicalName(ns.Hdr.Name) == dns.CanonicalName(name) {
+				names = append(names, ns.Ns)
+			}
+		}
+		if len(names) > 0 {
+			return names, nil
+		}
+
+		// never ask for the nameservers of the root
+		next, end := dns.NextLabel(name, 0)
+		if end {
+			return nil, errors.New("no nameservers found")
+		}
+		name = name[next:]
+	}
+}
+
+func (c *PropagationChecker) query(ctx context.Context, server, name string, qtype uint16) ([]dns.RR, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, qtype)
+
+	response, err := c.resolver().Exchange(ctx, msg, server)
+	if err != nil {
+		return nil, fmt.Errorf("query %s %s at %s: %w", name, dns.TypeToString[qtype], server, err)
+	}
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("query %s %s at %s: %s", name, dns.TypeToString[qtype], server, dns.RcodeToString[response.Rcode])
+	}
+	return response.Answer, nil
+}
+
+// visible asks a nameserver directly, without recursion, for the record
+func (c *PropagationChecker) visible(ctx context.Context, server, zone string, record libdns.Record) (bool, error) {
+	qtype, ok := dns.StringToType[strings.ToUpper(record.Type)]
+	if !ok {
+		return false, fmt.Errorf("unknown record type %s", record.Type)
+	}
+
+	name := asciiName(record.Name)
+	if name == "@" {
+		name = ""
+	}
+	fqdn := dns.Fqdn(libdns.AbsoluteName(name, zoneToFqdn(zone)))
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(fqdn, qtype)
+	msg.RecursionDesired = false
+
+	response, err := c.resolver().Exchange(ctx, msg, server)
+	if err != nil {
+		return false, err
+	}
+
+	for _, answer := range response.Answer {
+		if answerMatches(answer, record) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func answerMatches(answer dns.RR, record libdns.Record) bool {
+	sameHost := func(a, b string) bool {
+		return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(asciiName(b), "."))
+	}
+	sameAddr := func(addr net.IP, value string) bool {
+		expected, err := netip.ParseAddr(value)
+		actual, ok := netip.AddrFromSlice(addr)
+		return err == nil && ok && actual.Unmap() == expected.Unmap()
+	}
+
+	switch rr := answer.(type) {
+	case *dns.A:
+		return sameAddr(rr.A, record.Value)
+	case *dns.AAAA:
+		return sameAddr(rr.AAAA, record.Value)
+	case *dns.CNAME:
+		return sameHost(rr.Target, record.Value)
+	case *dns.MX:
+		return sameHost(rr.Mx, record.Value) && uint(rr.Preference) == record.Priority
+	case *dns.NS:
+		return sameHost(rr.Ns, record.Value)
+	case *dns.TXT:
+		return joinCharacterStrings(rr.Txt) == record.Value
+	case *dns.SPF:
+		return joinCharacterStrings(rr.Txt) == record.Value
+	default:
+		return false
+	}
+}
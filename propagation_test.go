@@ -0,0 +1,190 @@
+package dynu
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubNameserver is a local DNS server answering from a mutable record set
+type stubNameserver struct {
+	addr string
+
+	mutex   sync.Mutex
+	records []dns.RR
+}
+
+func newStubNameserver(t *testing.T, records ...string) *stubNameserver {
+	stub := &stubNameserver{}
+	stub.set(t, records...)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	stub.addr = conn.LocalAddr().String()
+
+	server := &dns.Server{PacketConn: conn, Handler: stub}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	return stub
+}
+
+func (s *stubNameserver) set(t *testing.T, records ...string) {
+	var rrs []dns.RR
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		require.NoError(t, err)
+		rrs = append(rrs, rr)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.records = rrs
+}
+
+func (s *stubNameserver) ServeDNS(w dns.ResponseWriter, request *dns.Msg) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	response := new(dns.Msg)
+	response.SetReply(request)
+	question := request.Question[0]
+	for _, rr := range s.records {
+		header := rr.Header()
+		if header.Rrtype == question.Qtype && dns.CanonicalName(header.Name) == dns.CanonicalName(question.Name) {
+			response.Answer = append(response.Answer, rr)
+		}
+	}
+	_ = w.WriteMsg(response)
+}
+
+// stubResolver sends queries for the well-known port 53 addresses to stubs
+type stubResolver struct {
+	servers map[string]*stubNameserver
+
+	mutex   sync.Mutex
+	queried []string
+}
+
+func (r *stubResolver) Exchange(ctx context.Context, msg *dns.Msg, server string) (*dns.Msg, error) {
+	r.mutex.Lock()
+	r.queried = append(r.queried, server)
+	r.mutex.Unlock()
+
+	stub, ok := r.servers[server]
+	if !ok {
+		return nil, &net.OpError{Op: "dial", Net: "udp", Err: net.UnknownNetworkError(server)}
+	}
+	response, _, err := (&dns.Client{Net: "udp"}).ExchangeContext(ctx, msg, stub.addr)
+	return response, err
+}
+
+func newPropagationTest(t *testing.T) (*PropagationChecker, *stubNameserver, *stubNameserver) {
+	recursive := newStubNameserver(t,
+		"example.com. 300 IN NS ns1.example.net.",
+		"example.com. 300 IN NS ns2.example.net.",
+		"ns1.example.net. 300 IN A 192.0.2.53",
+		"ns1.example.net. 300 IN AAAA 2001:db8::1",
+		"ns2.example.net. 300 IN AAAA 2001:db8::53",
+	)
+	ns1 := newStubNameserver(t)
+	ns2 := newStubNameserver(t)
+
+	checker := &PropagationChecker{
+		Resolver: &stubResolver{servers: map[string]*stubNameserver{
+			"10.0.0.1:53":       recursive,
+			"192.0.2.53:53":     ns1,
+			"[2001:db8::53]:53": ns2,
+		}},
+		RecursiveServer: "10.0.0.1:53",
+		Timeout:         2 * time.Second,
+		Interval:        10 * time.Millisecond,
+	}
+	return checker, ns1, ns2
+}
+
+func TestPropagationWait(t *testing.T) {
+	checker, ns1, ns2 := newPropagationTest(t)
+	ns1.set(t, `_acme-challenge.example.com. 120 IN TXT "token"`)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		ns2.set(t, `_acme-challenge.example.com. 120 IN TXT "other" `, `_acme-challenge.example.com. 120 IN TXT "token"`)
+	}()
+
+	err := checker.Wait(context.Background(), "example.com.", libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token"})
+	assert.NoError(t, err)
+
+	queried := checker.Resolver.(*stubResolver).queried
+	assert.Contains(t, queried, "192.0.2.53:53")
+	assert.Contains(t, queried, "[2001:db8::53]:53")
+}
+
+func TestPropagationWaitTimeout(t *testing.T) {
+	checker, ns1, _ := newPropagationTest(t)
+	checker.Timeout = 100 * time.Millisecond
+	ns1.set(t, `_acme-challenge.example.com. 120 IN TXT "token"`)
+
+	err := checker.Wait(context.Background(), "example.com.", libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token"})
+	assert.ErrorContains(t, err, "not visible on ns2.example.net after")
+	assert.NotContains(t, err.Error(), "ns1.example.net")
+}
+
+func TestPropagationWaitUnreachableAddress(t *testing.T) {
+	checker, ns1, ns2 := newPropagationTest(t)
+	ns1.set(t, `_acme-challenge.example.com. 120 IN TXT "token"`)
+	ns2.set(t, `_acme-challenge.example.com. 120 IN TXT "token"`)
+
+	// only the second address of ns1 can be reached
+	resolver := checker.Resolver.(*stubResolver)
+	resolver.servers["[2001:db8::1]:53"] = ns1
+	delete(resolver.servers, "192.0.2.53:53")
+
+	err := checker.Wait(context.Background(), "example.com.", libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token"})
+	assert.NoError(t, err)
+	assert.Contains(t, resolver.queried, "192.0.2.53:53")
+}
+
+func TestPropagationWaitZoneCut(t *testing.T) {
+	checker, ns1, ns2 := newPropagationTest(t)
+	ns1.set(t, `_acme-challenge.my.example.com. 120 IN TXT "token"`)
+	ns2.set(t, `_acme-challenge.my.example.com. 120 IN TXT "token"`)
+
+	// my.example.com has no NS records of its own
+	err := checker.Wait(context.Background(), "my.example.com.", libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token"})
+	assert.NoError(t, err)
+}
+
+func TestPropagationWaitNameservers(t *testing.T) {
+	checker, ns1, _ := newPropagationTest(t)
+	checker.Nameservers = []string{"192.0.2.53:53"}
+	ns1.set(t,
+		"example.com. 120 IN A 203.0.113.1",
+		"www.example.com. 120 IN CNAME example.com.",
+		"example.com. 120 IN MX 10 mail.example.com.",
+	)
+
+	ctx := context.Background()
+	assert.NoError(t, checker.Wait(ctx, "example.com.", libdns.Record{Type: "A", Name: "@", Value: "203.0.113.1"}))
+	assert.NoError(t, checker.Wait(ctx, "example.com.", libdns.Record{Type: "CNAME", Name: "www", Value: "example.com"}))
+	assert.NoError(t, checker.Wait(ctx, "example.com.", libdns.Record{Type: "MX", Name: "", Value: "mail.example.com.", Priority: 10}))
+
+	checker.Timeout = 50 * time.Millisecond
+	assert.Error(t, checker.Wait(ctx, "example.com.", libdns.Record{Type: "MX", Name: "", Value: "mail.example.com.", Priority: 20}))
+
+	assert.NotContains(t, checker.Resolver.(*stubResolver).queried, "10.0.0.1:53")
+}
+
+func TestPropagationNoNameservers(t *testing.T) {
+	checker, _, _ := newPropagationTest(t)
+
+	err := checker.Wait(context.Background(), "example.org.", libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token"})
+	assert.ErrorContains(t, err, "find nameservers of example.org.: no nameservers found")
+}
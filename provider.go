@@ -19,12 +19,28 @@ type Provider struct {
 	APIToken  string `json:"api_token,omitempty"`
 	OwnDomain string `json:"own_domain,omitempty"`
 
+	// UnicodeNames returns internationalised names in record names and
+	// targets in Unicode rather than punycode on reads.
+	UnicodeNames bool `json:"unicode_names,omitempty"`
+
+	// ApexAddresses reads the IPv4 and IPv6 addresses of the Dynu domain as
+	// apex A and AAAA records and writes apex A and AAAA records to them.
+	ApexAddresses bool `json:"apex_addresses,omitempty"`
+
+	// AuditSink, if set, receives an event for every change made.
+	AuditSink AuditSink `json:"-"`
+	// TTLPolicy normalises the TTLs of records written and read.
+	TTLPolicy TTLPolicy `json:"-"`
+
 	Once   sync.Once
 	Client *Client
 }
 
 func (p *Provider) init() {
-	p.Client = NewClient(p.APIToken)
+	// keep a Client configured by the caller, e.g. with Metrics set
+	if p.Client == nil {
+		p.Client = NewClient(p.APIToken)
+	}
 }
 
 // GetRecords lists all the records in the zone.
@@ -36,7 +52,7 @@ func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record
 	domain := zoneToFqdn(zone)
 
 	// GET /dns/getroot/{hostname}
-	dnsHostName, err := p.Client.GetRootDomain(ctx, p.OwnDomain)
+	dnsHostName, err := p.Client.GetRootDomain(ctx, p.ownDomainOf(zone))
 	if err != nil {
 		return nil, err
 	}
@@ -48,10 +64,16 @@ func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record
 	}
 
 	for _, dnsRecord := range dnsRecords {
-		libRecords = append(libRecords, dnsRecordToLibdnsRecord(dnsRecord, domain))
+		libRecords = append(libRecords, p.readRecord(dnsRecord, domain))
 	}
 
-	return libRecords, nil
+	// GET /dns/{id}, only with ApexAddresses
+	apexRecords, err := p.readApexRecords(ctx, dnsHostName.ID, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(libRecords, apexRecords...), nil
 }
 
 func dnsRecordToLibdnsRecord(dnsRecord DNSRecord, domain string) libdns.Record {
@@ -83,12 +105,17 @@ func dnsRecordToLibdnsRecord(dnsRecord DNSRecord, domain string) libdns.Record {
 	case "NS":
 		libRecord.Value = dnsRecord.Host
 	case "PTR":
-		libRecord.Name = dnsRecord.Host
-		libRecord.Value = dnsRecord.Hostname
+		if isReverseZone(dnsRecord.DomainName) || isReverseZone(domain) {
+			libRecord.Value = dnsRecord.Host
+		} else {
+			// kept on the hostname the record points to, see ptrNodeName
+			libRecord.Name = dnsRecord.Host
+			libRecord.Value = dnsRecord.Hostname
+		}
 	case "SPF":
-		libRecord.Value = dnsRecord.TextData
+		libRecord.Value = decodeTXT(dnsRecord.TextData)
 	case "TXT":
-		libRecord.Value = dnsRecord.TextData
+		libRecord.Value = decodeTXT(dnsRecord.TextData)
 	default:
 		libRecord.Value = dnsRecord.Content
 	}
@@ -117,25 +144,98 @@ func (p *Provider) appendOrSetRecords(ctx context.Context, zone string, records
 	domain := zoneToFqdn(zone)
 
 	// GET /dns/getroot/{hostname}
-	dnsHostName, err := p.Client.GetRootDomain(ctx, p.OwnDomain)
+	dnsHostName, err := p.Client.GetRootDomain(ctx, p.ownDomainOf(zone))
 	if err != nil {
 		return nil, err
 	}
 
-	for _, rec := range records {
-		dnsRecord, err := libdnsRecordToDnsRecord(rec, domain, p.OwnDomain)
+	operation := "set"
+	if ignoreRecordId {
+		operation = "append"
+	}
+
+	// the zone is only read to check CNAMEs against it and for audit events
+	var existing []libdns.Record
+	currentRecords := make(map[string]libdns.Record)
+	if writesCNAME(records) || p.AuditSink != nil {
+		// GET /dns/{id}/record
+		dnsRecords, err := p.Client.GetRecords(ctx, dnsHostName.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, dnsRecord := range dnsRecords {
+			record := dnsRecordToLibdnsRecord(dnsRecord, domain)
+			existing = append(existing, record)
+			currentRecords[record.ID] = record
+		}
+
+		// GET /dns/{id}, only with ApexAddresses
+		apexRecords, err := p.readApexRecords(ctx, dnsHostName.ID, domain)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range apexRecords {
+			existing = append(existing, record)
+			currentRecords[record.ID] = record
+		}
+	}
+
+	// appended records never replace existing ones
+	requested := records
+	if ignoreRecordId {
+		requested = make([]libdns.Record, len(records))
+		for i, rec := range records {
+			rec.ID = ""
+			requested[i] = rec
+		}
+	}
+	prepared, err := p.prepareRecords(zone, requested, existing)
+	if err != nil {
+		return nil, err
+	}
+
+	// rec is the requested record as validated, without ID when appending
+	for i, rec := range prepared {
+		var before *libdns.Record
+		if current, ok := currentRecords[rec.ID]; ok && !ignoreRecordId {
+			before = &current
+		}
+
+		dnsRecord, err := libdnsRecordToDnsRecord(rec, domain, p.ownDomainOf(domain))
 		if err != nil {
 			updateErrors = append(updateErrors, err)
+			if err := p.audit(ctx, operation, zone, dnsHostName.ID, before, rec, nil, err); err != nil {
+				updateErrors = append(updateErrors, fmt.Errorf("audit: %w", err))
+			}
 			continue
 		}
 
-		// POST /dns/{id}/record[/{dnsRecordId}]
-		updateResponse, err := p.Client.AddOrUpdateRecord(ctx, dnsHostName.ID, dnsRecord, ignoreRecordId)
+		var after *libdns.Record
+		if p.isApexAddress(rec, dnsRecord) {
+			// the domain-level address is replaced even without its ID
+			if current, ok := currentRecords[domainAddressID(rec.Type)]; ok && before == nil {
+				before = &current
+			}
+
+			// GET and POST /dns/{id}
+			after, err = p.writeApexAddress(ctx, dnsHostName.ID, domain, rec, ignoreRecordId, records[i].TTL != 0)
+		} else {
+			// POST /dns/{id}/record[/{dnsRecordId}]
+			var updateResponse *DNSRecord
+			if updateResponse, err = p.Client.AddOrUpdateRecord(ctx, dnsHostName.ID, dnsRecord, ignoreRecordId); err == nil {
+				updatedRecord := p.readRecord(*updateResponse, domain)
+				after = &updatedRecord
+			}
+		}
 
 		if err != nil {
 			updateErrors = append(updateErrors, fmt.Errorf("dnsRecord %+v: %w", rec, err))
 		} else {
-			updatedRecords = append(updatedRecords, dnsRecordToLibdnsRecord(*updateResponse, domain))
+			updatedRecords = append(updatedRecords, *after)
+		}
+
+		if err := p.audit(ctx, operation, zone, dnsHostName.ID, before, rec, after, err); err != nil {
+			updateErrors = append(updateErrors, fmt.Errorf("audit: %w", err))
 		}
 	}
 
@@ -146,14 +246,22 @@ func libdnsRecordToDnsRecord(record libdns.Record, domain string, ownDomain stri
 	var id int64
 	fmt.Sscan(record.ID, &id)
 
-	var nodeName = record.Name
+	var nodeName = asciiName(record.Name)
 	if nodeName == "@" {
 		nodeName = ""
 	}
+	domain, ownDomain = asciiName(domain), asciiName(ownDomain)
 
 	// sub.owndomain -> sub.owndomain.domain.com -> sub
 	var fqdn = libdns.AbsoluteName(nodeName, domain)
-	var relativeName = libdns.RelativeName(fqdn, ownDomain)
+	relativeName, err := relativeNode(fqdn, ownDomain)
+	if err != nil && record.Type != "PTR" {
+		if isWildcard(fqdn) {
+			return DNSRecord{}, fmt.Errorf("wildcard name %q must be below %s, the domain in Dynu", record.Name, ownDomain)
+		}
+		return DNSRecord{}, fmt.Errorf("record name %q: %w, the domain in Dynu", record.Name, err)
+	}
+	err = nil
 
 	dnsRecord := DNSRecord{
 		ID:       id,
@@ -163,27 +271,35 @@ func libdnsRecordToDnsRecord(record libdns.Record, domain string, ownDomain stri
 		State:    true, // must be set to true to take effect
 	}
 
-	var err error
-
 	switch record.Type {
 	case "A":
 		dnsRecord.Ipv4Address = record.Value
 	case "AAAA":
 		dnsRecord.Ipv6Address = record.Value
 	case "CNAME":
-		dnsRecord.Host = record.Value
+		dnsRecord.Host = asciiName(record.Value)
 	case "MX":
-		dnsRecord.Host = record.Value
+		dnsRecord.Host = asciiName(record.Value)
 		dnsRecord.Priority = int(record.Priority)
 	case "NS":
-		dnsRecord.Host = record.Value
+		dnsRecord.Host = asciiName(record.Value)
 	case "PTR":
-		dnsRecord.Host = record.Name
-		dnsRecord.NodeName = libdns.RelativeName(record.Value, ownDomain) // seems Dynu can only point to subdomain; get relative name from input
+		var reverseName string
+		if reverseName, err = ptrReverseName(record.Name, domain); err != nil {
+			break
+		}
+		if isReverseZone(ownDomain) {
+			// a reverse zone in Dynu keeps PTR records on their reverse name
+			dnsRecord.NodeName, err = relativeNode(reverseName, ownDomain)
+			dnsRecord.Host = asciiName(record.Value)
+			break
+		}
+		dnsRecord.Host = reverseName
+		dnsRecord.NodeName, err = ptrNodeName(record.Value, ownDomain)
 	case "SPF":
-		dnsRecord.TextData = record.Value
+		dnsRecord.TextData = encodeTXT(record.Value)
 	case "TXT":
-		dnsRecord.TextData = record.Value
+		dnsRecord.TextData = encodeTXT(record.Value)
 	default:
 		err = fmt.Errorf("dnsRecord %+v: record type not implemented", record)
 	}
@@ -199,28 +315,58 @@ func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []lib
 	var deleteErrors []error
 
 	// GET /dns/getroot/{hostname}
-	dnsHostName, err := p.Client.GetRootDomain(ctx, p.OwnDomain)
+	dnsHostName, err := p.Client.GetRootDomain(ctx, p.ownDomainOf(zone))
+	if err != nil {
+		return nil, err
+	}
+
+	// GET /dns/{id}/record, only when auditing
+	currentRecords, err := p.currentRecords(ctx, dnsHostName.ID, zoneToFqdn(zone))
 	if err != nil {
 		return nil, err
 	}
 
 	// DELETE /dns/{id}/record/{dnsRecordId}
 	for _, rec := range records {
-		err := p.Client.DeleteRecord(ctx, dnsHostName.ID, rec.ID)
+		var err error
+		if p.ApexAddresses && isDomainAddressID(rec.ID) {
+			// GET and POST /dns/{id}
+			err = p.deleteApexAddress(ctx, dnsHostName.ID, rec.ID)
+		} else {
+			err = p.Client.DeleteRecord(ctx, dnsHostName.ID, rec.ID)
+		}
 
 		if err != nil {
 			deleteErrors = append(deleteErrors, fmt.Errorf("dnsRecordId %s: %w", rec.ID, err))
 		} else {
 			deletedRecords = append(deletedRecords, rec)
 		}
+
+		var before *libdns.Record
+		if current, ok := currentRecords[rec.ID]; ok {
+			before = &current
+		}
+		if err := p.audit(ctx, "delete", zone, dnsHostName.ID, before, rec, nil, err); err != nil {
+			deleteErrors = append(deleteErrors, fmt.Errorf("audit: %w", err))
+		}
 	}
 
 	return deletedRecords, errors.Join(deleteErrors...)
 }
 
+// ownDomainOf returns the Dynu domain of the zone: OwnDomain, or the zone
+// itself if OwnDomain is not set
+func (p *Provider) ownDomainOf(zone string) string {
+	if p.OwnDomain != "" {
+		return p.OwnDomain
+	}
+	return zoneToFqdn(zone)
+}
+
 func zoneToFqdn(zone string) string {
-	// we trim the dot at the end of the zone name to get the fqdn
-	return strings.TrimRight(zone, ".")
+	// we trim the dot at the end of the zone name to get the fqdn, which
+	// Dynu knows in punycode
+	return asciiName(strings.TrimRight(zone, "."))
 }
 
 // Interface guards
@@ -3,32 +3,67 @@ package dynu
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/libdns/libdns"
 	"github.com/stretchr/testify/assert"
+	"github.com/taviowong/libdns-dynu/cassette"
 )
 
 var zone = os.Getenv("TEST_ZONE")
 var apiToken = os.Getenv("TEST_API_TOKEN")
 var testRealApi = zone != "" && apiToken != ""
+var recordCassettes = os.Getenv("TEST_RECORD") != ""
 
 var domain = "dynu.com"
 var ownDomain = "my.dynu.com"
 
-func checkSkipApiTest(t *testing.T) {
-	if !testRealApi {
-		t.Skip("Env variables not set. Skipping api test.")
+// zone used by the cassettes in place of TEST_ZONE
+var cassetteZone = "example.com."
+
+// newApiTestProvider returns a provider for the real API if the env variables
+// are set, recording the interactions to testdata/cassettes/<name>.json when
+// TEST_RECORD is set too. Otherwise the interactions are replayed from the
+// cassette.
+func newApiTestProvider(t *testing.T, name string) (*Provider, string) {
+	path := filepath.Join("testdata", "cassettes", name+".json")
+
+	if testRealApi {
+		provider := &Provider{APIToken: apiToken, OwnDomain: zoneToFqdn(zone)}
+		if recordCassettes {
+			recorder := &cassette.Recorder{Redactions: map[string]string{
+				zoneToFqdn(zone): zoneToFqdn(cassetteZone),
+				apiToken:         "api-token",
+			}}
+			provider.Client = NewClient(apiToken)
+			provider.Client.HTTPClient.Transport = recorder
+			t.Cleanup(func() {
+				if err := recorder.Save(path); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+		return provider, zone
 	}
+
+	replayer, err := cassette.Load(path)
+	if err != nil {
+		t.Skipf("Env variables not set and no cassette: %v. Skipping api test.", err)
+	}
+	t.Cleanup(func() { assert.NoError(t, replayer.Done()) })
+
+	provider := &Provider{APIToken: "api-token", OwnDomain: zoneToFqdn(cassetteZone)}
+	provider.Client = NewClient(provider.APIToken)
+	provider.Client.HTTPClient.Transport = replayer
+	return provider, cassetteZone
 }
 
 func TestGetRecords(t *testing.T) {
-	checkSkipApiTest(t)
-
 	ctx := context.TODO()
 
-	provider := Provider{APIToken: apiToken, OwnDomain: zoneToFqdn(zone)}
+	provider, zone := newApiTestProvider(t, "get_records")
 
 	recs, err := provider.GetRecords(ctx, zone)
 
@@ -42,11 +77,9 @@ func TestGetRecords(t *testing.T) {
 }
 
 func TestAddAndDeleteTxtRecord(t *testing.T) {
-	checkSkipApiTest(t)
-
 	ctx := context.TODO()
 
-	provider := Provider{APIToken: apiToken, OwnDomain: zoneToFqdn(zone)}
+	provider, zone := newApiTestProvider(t, "add_and_delete_txt_record")
 	testRecord := libdns.Record{
 		Type:  "TXT",
 		Name:  "@",
@@ -79,11 +112,9 @@ func TestAddAndDeleteTxtRecord(t *testing.T) {
 }
 
 func TestAddUpdateAndDeleteTxtRecord(t *testing.T) {
-	checkSkipApiTest(t)
-
 	ctx := context.TODO()
 
-	provider := Provider{APIToken: apiToken, OwnDomain: zoneToFqdn(zone)}
+	provider, zone := newApiTestProvider(t, "add_update_and_delete_txt_record")
 	testRecord := libdns.Record{
 		Type:  "TXT",
 		Name:  "test",
@@ -446,3 +477,18 @@ func getBasicLibDnsRecord() libdns.Record {
 		TTL:  time.Duration(120) * time.Second,
 	}
 }
+
+func TestProviderWithoutOwnDomain(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.", DNSRecord{Type: "TXT", NodeName: "www", TextData: "value", TTL: 300})
+	provider.OwnDomain = ""
+
+	records, err := provider.GetRecords(context.TODO(), "example.com.")
+	if assert.NoError(t, err) && assert.Len(t, records, 1) {
+		assert.Equal(t, "www", records[0].Name)
+	}
+
+	_, err = provider.AppendRecords(context.TODO(), "example.com.", []libdns.Record{{Type: "TXT", Name: "api", Value: "value"}})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "api.example.com", fake.Records()[1].Hostname)
+	}
+}
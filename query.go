@@ -0,0 +1,145 @@
+package dynu
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// RecordQuery selects records in Provider.QueryRecords. Empty fields match
+// all records; set fields must all match.
+type RecordQuery struct {
+	// Name is the exact name relative to the zone, "@" for the apex.
+	Name string
+	// NameSuffix matches the name itself and all names below it, e.g. "dev"
+	// matches "dev", "api.dev" and "*.dev".
+	NameSuffix string
+	// NamePattern matches names with path.Match, e.g. "_acme-challenge*".
+	NamePattern string
+	// Types matches any of the record types.
+	Types []string
+	// Value matches record values.
+	Value *regexp.Regexp
+	// State matches enabled (true) or disabled (false) records.
+	State *bool
+}
+
+// QueryRecords returns the records of the zone matching the query. Queries
+// for a single name and at most one type use Dynu's per-hostname endpoint;
+// others fetch the whole zone and filter.
+func (p *Provider) QueryRecords(ctx context.Context, zone string, query RecordQuery) ([]libdns.Record, error) {
+	p.Once.Do(func() { p.init() })
+
+	if _, err := path.Match(query.NamePattern, ""); err != nil {
+		return nil, err
+	}
+
+	domain := zoneToFqdn(zone)
+
+	var dnsRecords, apexRecords []DNSRecord
+	var err error
+	if query.Name != "" && len(query.Types) <= 1 {
+		recordType := ""
+		if len(query.Types) == 1 {
+			recordType = strings.ToUpper(query.Types[0])
+		}
+
+		name := query.Name
+		if name == "@" {
+			name = ""
+		}
+
+		// GET /dns/record/{hostname}?recordType={type}
+		dnsRecords, err = p.Client.GetRecordsByHostname(ctx, libdns.AbsoluteName(name, domain), recordType)
+	} else {
+		// GET /dns/getroot/{hostname}
+		var dnsHostName *DNSHostname
+		dnsHostName, err = p.Client.GetRootDomain(ctx, p.ownDomainOf(zone))
+		if err != nil {
+			return nil, err
+		}
+
+		// GET /dns/{id}/record
+		dnsRecords, err = p.Client.GetRecords(ctx, dnsHostName.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the domain-level addresses are always enabled
+	if p.ApexAddresses && (query.State == nil || *query.State) {
+		// GET /dns/getroot/{hostname}
+		dnsHostName, err := p.Client.GetRootDomain(ctx, p.ownDomainOf(zone))
+		if err != nil {
+			return nil, err
+		}
+
+		// GET /dns/{id}
+		dnsDomain, err := p.Client.GetDomain(ctx, dnsHostName.ID)
+		if err != nil {
+			return nil, err
+		}
+		apexRecords = domainAddressRecords(*dnsDomain)
+	}
+
+	var libRecords []libdns.Record
+	for _, dnsRecord := range dnsRecords {
+		if query.State != nil && dnsRecord.State != *query.State {
+			continue
+		}
+
+		record := p.readRecord(dnsRecord, domain)
+		if query.matches(record) {
+			libRecords = append(libRecords, record)
+		}
+	}
+	for _, dnsRecord := range apexRecords {
+		if record := p.readApexRecord(dnsRecord, domain); query.matches(record) {
+			libRecords = append(libRecords, record)
+		}
+	}
+
+	return libRecords, nil
+}
+
+func (q RecordQuery) matches(record libdns.Record) bool {
+	if q.Name != "" && !strings.EqualFold(asciiName(record.Name), asciiName(q.Name)) {
+		return false
+	}
+
+	if q.NameSuffix != "" {
+		suffix := strings.ToLower(asciiName(q.NameSuffix))
+		name := strings.ToLower(asciiName(record.Name))
+		if name != suffix && !strings.HasSuffix(name, "."+suffix) && suffix != "@" {
+			return false
+		}
+	}
+
+	if q.NamePattern != "" {
+		if matched, _ := path.Match(q.NamePattern, record.Name); !matched {
+			return false
+		}
+	}
+
+	if len(q.Types) > 0 {
+		found := false
+		for _, recordType := range q.Types {
+			if strings.EqualFold(recordType, record.Type) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if q.Value != nil && !q.Value.MatchString(record.Value) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,73 @@
+package dynu
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/libdns/libdns"
+	"github.com/stretchr/testify/assert"
+)
+
+func newQueryTestProvider(t *testing.T) (*Provider, *fakeDynu) {
+	return newFakeProvider(t, "example.com.",
+		DNSRecord{ID: 1, Type: "A", Ipv4Address: "203.0.113.1", TTL: 300, State: true},
+		DNSRecord{ID: 2, Type: "A", NodeName: "www", Ipv4Address: "203.0.113.1", TTL: 300, State: true},
+		DNSRecord{ID: 3, Type: "AAAA", NodeName: "www", Ipv6Address: "2001:db8::1", TTL: 300, State: true},
+		DNSRecord{ID: 4, Type: "A", NodeName: "api.dev", Ipv4Address: "198.51.100.1", TTL: 300, State: true},
+		DNSRecord{ID: 5, Type: "TXT", NodeName: "_acme-challenge.dev", TextData: "token", TTL: 120, State: false},
+	)
+}
+
+func recordIDs(records []libdns.Record) []string {
+	ids := []string{}
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	return ids
+}
+
+func TestQueryRecordsByHostname(t *testing.T) {
+	provider, fake := newQueryTestProvider(t)
+
+	records, err := provider.QueryRecords(context.TODO(), "example.com.", RecordQuery{Name: "www", Types: []string{"aaaa"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []string{"3"}, recordIDs(records))
+	assert.Equal(t, []string{"GET /dns/record/www.example.com"}, fake.Requests(), "only the hostname must be fetched")
+
+	records, err = provider.QueryRecords(context.TODO(), "example.com.", RecordQuery{Name: "@"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"1"}, recordIDs(records))
+	}
+}
+
+func TestQueryRecordsClientSide(t *testing.T) {
+	provider, _ := newQueryTestProvider(t)
+	disabled := false
+
+	tests := map[string]struct {
+		query RecordQuery
+		ids   []string
+	}{
+		"suffix":      {RecordQuery{NameSuffix: "dev"}, []string{"4", "5"}},
+		"pattern":     {RecordQuery{NamePattern: "_acme-challenge.*"}, []string{"5"}},
+		"types":       {RecordQuery{Types: []string{"A", "TXT"}}, []string{"1", "2", "4", "5"}},
+		"value":       {RecordQuery{Value: regexp.MustCompile(`^203\.0\.113\.`)}, []string{"1", "2"}},
+		"state":       {RecordQuery{State: &disabled}, []string{"5"}},
+		"name types":  {RecordQuery{Name: "www", Types: []string{"A", "AAAA"}}, []string{"2", "3"}},
+		"no matches":  {RecordQuery{NameSuffix: "staging"}, []string{}},
+		"combination": {RecordQuery{NameSuffix: "dev", Types: []string{"A"}}, []string{"4"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			records, err := provider.QueryRecords(context.TODO(), "example.com.", test.query)
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.ids, recordIDs(records))
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package dynu
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+const (
+	ipv4ReverseSuffix = ".in-addr.arpa"
+	ipv6ReverseSuffix = ".ip6.arpa"
+)
+
+// ReverseName returns the reverse DNS name of an address, e.g.
+// 1.2.0.192.in-addr.arpa for 192.0.2.1, without trailing dot like the PTR
+// record names read from Dynu.
+func ReverseName(addr netip.Addr) string {
+	addr = addr.Unmap()
+
+	var labels []string
+	if addr.Is4() {
+		octets := addr.As4()
+		for i := len(octets) - 1; i >= 0; i-- {
+			labels = append(labels, strconv.Itoa(int(octets[i])))
+		}
+		return strings.Join(labels, ".") + ipv4ReverseSuffix
+	}
+
+	const hex = "0123456789abcdef"
+	bytes := addr.As16()
+	for i := len(bytes) - 1; i >= 0; i-- {
+		labels = append(labels, string(hex[bytes[i]&0xf]), string(hex[bytes[i]>>4]))
+	}
+	return strings.Join(labels, ".") + ipv6ReverseSuffix
+}
+
+// ParseReverseName returns the address of a complete in-addr.arpa or
+// ip6.arpa name, with or without trailing dot.
+func ParseReverseName(name string) (netip.Addr, error) {
+	lower := strings.ToLower(strings.TrimSuffix(name, "."))
+
+	switch {
+	case strings.HasSuffix(lower, ipv4ReverseSuffix):
+		labels := strings.Split(strings.TrimSuffix(lower, ipv4ReverseSuffix), ".")
+		if len(labels) != 4 {
+			return netip.Addr{}, fmt.Errorf("%q is not the reverse name of an IPv4 address", name)
+		}
+		var octets [4]byte
+		for i, label := range labels {
+			octet, err := strconv.ParseUint(label, 10, 8)
+			if err != nil || (len(label) > 1 && label[0] == '0') {
+				return netip.Addr{}, fmt.Errorf("%q is not the reverse name of an IPv4 address", name)
+			}
+			octets[3-i] = byte(octet)
+		}
+		return netip.AddrFrom4(octets), nil
+
+	case strings.HasSuffix(lower, ipv6ReverseSuffix):
+		labels := strings.Split(strings.TrimSuffix(lower, ipv6ReverseSuffix), ".")
+		if len(labels) != 32 {
+			return netip.Addr{}, fmt.Errorf("%q is not the reverse name of an IPv6 address", name)
+		}
+		var bytes [16]byte
+		for i, label := range labels {
+			nibble, err := strconv.ParseUint(label, 16, 4)
+			if err != nil || len(label) != 1 {
+				return netip.Addr{}, fmt.Errorf("%q is not the reverse name of an IPv6 address", name)
+			}
+			bytes[15-i/2] |= byte(nibble) << (4 * (i % 2))
+		}
+		return netip.AddrFrom16(bytes), nil
+	}
+
+	return netip.Addr{}, fmt.Errorf("%q is not an in-addr.arpa or ip6.arpa name", name)
+}
+
+// isReverseZone reports whether a domain is (below) in-addr.arpa or ip6.arpa
+func isReverseZone(domain string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	return strings.HasSuffix("."+domain, ipv4ReverseSuffix) || strings.HasSuffix("."+domain, ipv6ReverseSuffix)
+}
+
+// ptrReverseName returns the absolute reverse name of a PTR record, whose
+// name is either absolute or relative to a reverse zone
+func ptrReverseName(name, domain string) (string, error) {
+	var fqdn string
+	switch {
+	case isReverseZone(name):
+		fqdn = strings.TrimSuffix(name, ".")
+	case isReverseZone(domain):
+		if name == "@" {
+			name = ""
+		}
+		fqdn = libdns.AbsoluteName(name, domain)
+	default:
+		return "", fmt.Errorf("PTR name %q must be an in-addr.arpa or ip6.arpa name, e.g. from ReverseName", name)
+	}
+
+	if _, err := ParseReverseName(fqdn); err != nil {
+		return "", fmt.Errorf("PTR name: %w", err)
+	}
+	return fqdn, nil
+}
+
+// ptrNodeName returns the node of a forward domain a PTR record is kept on
+// by Dynu: the hostname the record points to, which must be in the domain
+func ptrNodeName(hostname, ownDomain string) (string, error) {
+	node, err := relativeNode(hostname, ownDomain)
+	if err != nil {
+		return "", fmt.Errorf("PTR value: %w, as Dynu keeps PTR records of forward domains on the hostname they point to", err)
+	}
+	return node, nil
+}
+
+// relativeNode returns the name of a hostname relative to the domain it must
+// be in, "" for the domain itself
+func relativeNode(hostname, domain string) (string, error) {
+	hostname = strings.TrimSuffix(asciiName(hostname), ".")
+	domain = strings.TrimSuffix(asciiName(domain), ".")
+
+	lowerHostname, lowerDomain := strings.ToLower(hostname), strings.ToLower(domain)
+	switch {
+	case lowerHostname == lowerDomain:
+		return "", nil
+	case strings.HasSuffix(lowerHostname, "."+lowerDomain):
+		return hostname[:len(hostname)-len(domain)-1], nil
+	default:
+		return "", fmt.Errorf("%q is not in %s", hostname, domain)
+	}
+}
@@ -0,0 +1,156 @@
+package dynu
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReverseName(t *testing.T) {
+	assert.Equal(t, "1.2.0.192.in-addr.arpa", ReverseName(netip.MustParseAddr("192.0.2.1")))
+	assert.Equal(t, "1.2.0.192.in-addr.arpa", ReverseName(netip.MustParseAddr("::ffff:192.0.2.1")))
+	assert.Equal(t, "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa",
+		ReverseName(netip.MustParseAddr("2001:db8::1")))
+}
+
+func TestParseReverseName(t *testing.T) {
+	addr, err := ParseReverseName("1.2.0.192.IN-ADDR.ARPA.")
+	if assert.NoError(t, err) {
+		assert.Equal(t, netip.MustParseAddr("192.0.2.1"), addr)
+	}
+
+	for _, name := range []string{
+		"2.0.192.in-addr.arpa",
+		"01.2.0.192.in-addr.arpa",
+		"256.2.0.192.in-addr.arpa",
+		"1.0.8.b.d.0.1.0.0.2.ip6.arpa",
+		"1.2.0.192.example.com",
+	} {
+		_, err := ParseReverseName(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestReverseNameRoundTrip(t *testing.T) {
+	ipv4 := func(octets [4]byte) bool {
+		addr := netip.AddrFrom4(octets)
+		parsed, err := ParseReverseName(ReverseName(addr))
+		return err == nil && parsed == addr
+	}
+	ipv6 := func(bytes [16]byte) bool {
+		addr := netip.AddrFrom16(bytes)
+		parsed, err := ParseReverseName(ReverseName(addr))
+		return err == nil && parsed == addr.Unmap()
+	}
+	assert.NoError(t, quick.Check(ipv4, nil))
+	assert.NoError(t, quick.Check(ipv6, nil))
+}
+
+func TestPTRRecordRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		zone      string
+		ownDomain string
+		record    libdns.Record
+		expected  DNSRecord
+	}{
+		{
+			name:      "forward domain",
+			zone:      "dynu.com",
+			ownDomain: "my.dynu.com",
+			record:    libdns.Record{Type: "PTR", Name: "1.2.0.192.in-addr.arpa", Value: "abc.my.dynu.com"},
+			expected:  DNSRecord{Type: "PTR", NodeName: "abc", Host: "1.2.0.192.in-addr.arpa"},
+		},
+		{
+			name:      "forward domain apex IPv6",
+			zone:      "my.dynu.com",
+			ownDomain: "my.dynu.com",
+			record:    libdns.Record{Type: "PTR", Name: ReverseName(netip.MustParseAddr("2001:db8::1")), Value: "my.dynu.com"},
+			expected:  DNSRecord{Type: "PTR", NodeName: "", Host: ReverseName(netip.MustParseAddr("2001:db8::1"))},
+		},
+		{
+			name:      "reverse zone",
+			zone:      "2.0.192.in-addr.arpa.",
+			ownDomain: "2.0.192.in-addr.arpa",
+			record:    libdns.Record{Type: "PTR", Name: "1", Value: "host.example.com"},
+			expected:  DNSRecord{Type: "PTR", NodeName: "1", Host: "host.example.com"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.record.ID = "42"
+			test.record.TTL = 5 * time.Minute
+			test.expected.ID = 42
+			test.expected.TTL = 300
+			test.expected.State = true
+
+			dnsRecord, err := libdnsRecordToDnsRecord(test.record, zoneToFqdn(test.zone), test.ownDomain)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, test.expected, dnsRecord)
+
+			// as returned by Dynu
+			dnsRecord.DomainName = test.ownDomain
+			dnsRecord.Hostname = test.ownDomain
+			if dnsRecord.NodeName != "" {
+				dnsRecord.Hostname = dnsRecord.NodeName + "." + test.ownDomain
+			}
+			assert.Equal(t, test.record, dnsRecordToLibdnsRecord(dnsRecord, zoneToFqdn(test.zone)))
+		})
+	}
+}
+
+func TestPTRRecordErrors(t *testing.T) {
+	tests := []struct {
+		zone, ownDomain string
+		record          libdns.Record
+		err             string
+	}{
+		{"dynu.com", "my.dynu.com", libdns.Record{Type: "PTR", Name: "1.2.0.192.in-addr.arpa", Value: "mail.example.net"},
+			`PTR value: "mail.example.net" is not in my.dynu.com, as Dynu keeps PTR records of forward domains on the hostname they point to`},
+		{"dynu.com", "my.dynu.com", libdns.Record{Type: "PTR", Name: "abc.my", Value: "abc.my.dynu.com"},
+			`PTR name "abc.my" must be an in-addr.arpa or ip6.arpa name, e.g. from ReverseName`},
+		{"dynu.com", "my.dynu.com", libdns.Record{Type: "PTR", Name: "2.0.192.in-addr.arpa", Value: "abc.my.dynu.com"},
+			`PTR name: "2.0.192.in-addr.arpa" is not the reverse name of an IPv4 address`},
+		{"2.0.192.in-addr.arpa", "2.0.192.in-addr.arpa", libdns.Record{Type: "PTR", Name: "1.3.0.192.in-addr.arpa", Value: "host.example.com"},
+			`"1.3.0.192.in-addr.arpa" is not in 2.0.192.in-addr.arpa`},
+	}
+
+	for _, test := range tests {
+		_, err := libdnsRecordToDnsRecord(test.record, test.zone, test.ownDomain)
+		assert.EqualError(t, err, test.err)
+	}
+}
+
+func TestAppendPTRRecordOutsideOwnDomain(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.")
+
+	_, err := provider.AppendRecords(context.TODO(), "example.com.", []libdns.Record{
+		{Type: "PTR", Name: "1.2.0.192.in-addr.arpa", Value: "www.example.com"},
+		{Type: "PTR", Name: "2.2.0.192.in-addr.arpa", Value: "www.example.net"},
+		{Type: "PTR", Name: "www", Value: "www.example.com"},
+	})
+	if assert.Error(t, err) {
+		violations := err.(*ValidationError).Violations
+		if assert.Len(t, violations, 2) {
+			assert.True(t, strings.HasPrefix(violations[0].Reason, `PTR value: "www.example.net" is not in example.com`), violations[0].Reason)
+			assert.True(t, strings.HasPrefix(violations[1].Reason, `PTR name "www" must be`), violations[1].Reason)
+		}
+	}
+	assert.Empty(t, fake.Records())
+}
+
+func TestImportReverseZonePTR(t *testing.T) {
+	parsed, err := ParseZoneFile(strings.NewReader("$ORIGIN 2.0.192.in-addr.arpa.\n1 300 IN PTR host.example.com.\n"), "2.0.192.in-addr.arpa.")
+	if assert.NoError(t, err) && assert.Len(t, parsed.Records, 1) {
+		assert.Equal(t, libdns.Record{Type: "PTR", Name: "1", Value: "host.example.com", TTL: 5 * time.Minute}, parsed.Records[0])
+	}
+}
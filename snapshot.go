@@ -0,0 +1,326 @@
+package dynu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// SnapshotVersion is the format version written by Provider.Snapshot.
+const SnapshotVersion = 1
+
+// Snapshot is a serialisable copy of all Dynu records of a zone.
+type Snapshot struct {
+	Version  int       `json:"version"`
+	Zone     string    `json:"zone"`
+	DomainID int64     `json:"domainId"`
+	TakenAt  time.Time `json:"takenAt"`
+	// Records are kept as the JSON returned by Dynu, including IDs, state and
+	// fields DNSRecord does not model, such as updatedOn.
+	Records []json.RawMessage `json:"records"`
+	// Domain holds the settings of the Dynu domain, including its IPv4 and
+	// IPv6 addresses, for snapshots taken with Provider.ApexAddresses.
+	Domain *Domain `json:"domain,omitempty"`
+}
+
+// WriteTo writes the snapshot as JSON.
+func (s *Snapshot) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// DNSRecords decodes the records of the snapshot.
+func (s *Snapshot) DNSRecords() ([]DNSRecord, error) {
+	dnsRecords := make([]DNSRecord, len(s.Records))
+	for i, record := range s.Records {
+		if err := json.Unmarshal(record, &dnsRecords[i]); err != nil {
+			return nil, fmt.Errorf("snapshot: record %s: %w", record, err)
+		}
+	}
+	return dnsRecords, nil
+}
+
+// ReadSnapshot reads a snapshot written by Snapshot.WriteTo.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	snapshot := &Snapshot{}
+	if err := json.NewDecoder(r).Decode(snapshot); err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	if snapshot.Version != SnapshotVersion {
+		return nil, fmt.Errorf("snapshot: unsupported version %d", snapshot.Version)
+	}
+	return snapshot, nil
+}
+
+// Snapshot takes a snapshot of all records of the zone.
+func (p *Provider) Snapshot(ctx context.Context, zone string) (*Snapshot, error) {
+	p.Once.Do(func() { p.init() })
+
+	// GET /dns/getroot/{hostname}
+	dnsHostName, err := p.Client.GetRootDomain(ctx, p.ownDomainOf(zone))
+	if err != nil {
+		return nil, err
+	}
+
+	// GET /dns/{id}/record
+	rawRecords, err := p.Client.GetRawRecords(ctx, dnsHostName.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var dnsDomain *Domain
+	if p.ApexAddresses {
+		// GET /dns/{id}
+		if dnsDomain, err = p.Client.GetDomain(ctx, dnsHostName.ID); err != nil {
+			return nil, err
+		}
+		dnsDomain.StatusCode = 0
+	}
+
+	return &Snapshot{
+		Version:  SnapshotVersion,
+		Zone:     zone,
+		DomainID: dnsHostName.ID,
+		TakenAt:  time.Now().UTC(),
+		Records:  rawRecords,
+		Domain:   dnsDomain,
+	}, nil
+}
+
+// RestoreChange is a change made by Provider.Restore. Before is nil for
+// creations, After for deletions. Changes of the domain-level addresses, see
+// Provider.ApexAddresses, have records with ID 0.
+type RestoreChange struct {
+	Action SyncAction
+	Before *DNSRecord
+	After  *DNSRecord
+	// Err is set if the change failed.
+	Err error
+}
+
+// Restore reconciles the zone with the snapshot. Records still present with
+// the same ID are updated if they differ, missing records are re-created
+// (with new IDs) unless an identical record exists, and records not in the
+// snapshot are deleted. IDs are only relied upon when restoring into the
+// domain the snapshot was taken from. With Provider.ApexAddresses, the
+// domain-level addresses and the TTL of the domain are restored as well if
+// the snapshot has them.
+func (p *Provider) Restore(ctx context.Context, zone string, snapshot *Snapshot) ([]RestoreChange, error) {
+	p.Once.Do(func() { p.init() })
+
+	if snapshot.Version != SnapshotVersion {
+		return nil, fmt.Errorf("snapshot: unsupported version %d", snapshot.Version)
+	}
+
+	domain := zoneToFqdn(zone)
+
+	// GET /dns/getroot/{hostname}
+	dnsHostName, err := p.Client.GetRootDomain(ctx, p.ownDomainOf(zone))
+	if err != nil {
+		return nil, err
+	}
+
+	// GET /dns/{id}/record
+	live, err := p.Client.GetRecords(ctx, dnsHostName.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted, err := snapshot.DNSRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	changes := planRestore(live, wanted, snapshot.DomainID == dnsHostName.ID)
+
+	if p.ApexAddresses && snapshot.Domain != nil {
+		// GET /dns/{id}
+		dnsDomain, err := p.Client.GetDomain(ctx, dnsHostName.ID)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, planApexRestore(*dnsDomain, *snapshot.Domain)...)
+	}
+
+	var errs []error
+	for _, action := range []SyncAction{SyncUpdate, SyncDelete, SyncCreate} {
+		for i := range changes {
+			change := &changes[i]
+			if change.Action != action {
+				continue
+			}
+
+			change.Err = p.applyRestoreChange(ctx, zone, dnsHostName.ID, domain, change, snapshot.Domain)
+			if change.Err != nil {
+				errs = append(errs, fmt.Errorf("restore %s record %s: %w", change.Action, restoreRecordID(change), change.Err))
+			}
+		}
+	}
+
+	return changes, errors.Join(errs...)
+}
+
+func restoreRecordID(change *RestoreChange) string {
+	record := change.After
+	if change.Before != nil {
+		record = change.Before
+	}
+	if record.ID == 0 {
+		return domainAddressID(record.Type)
+	}
+	return fmt.Sprint(record.ID)
+}
+
+// planRestore returns the minimal changes turning live into wanted
+func planRestore(live, wanted []DNSRecord, sameDomain bool) []RestoreChange {
+	var changes []RestoreChange
+
+	liveByID := make(map[int64]int, len(live))
+	for i, record := range live {
+		liveByID[record.ID] = i
+	}
+	matched := make([]bool, len(live))
+
+	var missing []DNSRecord
+	for _, record := range wanted {
+		i, ok := liveByID[record.ID]
+		if !ok || !sameDomain {
+			missing = append(missing, record)
+			continue
+		}
+
+		matched[i] = true
+		if writableDNSRecord(live[i]) != writableDNSRecord(record) {
+			before, after := live[i], record
+			changes = append(changes, RestoreChange{Action: SyncUpdate, Before: &before, After: &after})
+		}
+	}
+
+	for _, record := range missing {
+		found := false
+		for i := range live {
+			if !matched[i] && sameRestoreContent(live[i], record) {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			after := record
+			changes = append(changes, RestoreChange{Action: SyncCreate, After: &after})
+		}
+	}
+
+	for i := range live {
+		if !matched[i] {
+			before := live[i]
+			changes = append(changes, RestoreChange{Action: SyncDelete, Before: &before})
+		}
+	}
+
+	return changes
+}
+
+// planApexRestore returns the changes of the domain-level addresses turning
+// live into wanted
+func planApexRestore(live, wanted Domain) []RestoreChange {
+	byType := func(domain Domain) map[string]DNSRecord {
+		records := make(map[string]DNSRecord)
+		for _, record := range domainAddressRecords(domain) {
+			records[record.Type] = record
+		}
+		return records
+	}
+	liveByType, wantedByType := byType(live), byType(wanted)
+
+	var changes []RestoreChange
+	for _, recordType := range []string{"A", "AAAA"} {
+		before, inLive := liveByType[recordType]
+		after, inWanted := wantedByType[recordType]
+		switch {
+		case inLive && inWanted && sameRestoreContent(before, after):
+		case inLive && inWanted:
+			changes = append(changes, RestoreChange{Action: SyncUpdate, Before: &before, After: &after})
+		case inWanted:
+			changes = append(changes, RestoreChange{Action: SyncCreate, After: &after})
+		case inLive:
+			changes = append(changes, RestoreChange{Action: SyncDelete, Before: &before})
+		}
+	}
+	return changes
+}
+
+// writableDNSRecord keeps the fields sent when adding or updating a record
+func writableDNSRecord(record DNSRecord) DNSRecord {
+	return DNSRecord{
+		ID:          record.ID,
+		Type:        record.Type,
+		NodeName:    record.NodeName,
+		State:       record.State,
+		Ipv4Address: record.Ipv4Address,
+		Ipv6Address: record.Ipv6Address,
+		Host:        record.Host,
+		TextData:    record.TextData,
+		TTL:         record.TTL,
+		Priority:    record.Priority,
+	}
+}
+
+func sameRestoreContent(a, b DNSRecord) bool {
+	a, b = writableDNSRecord(a), writableDNSRecord(b)
+	a.ID, b.ID = 0, 0
+	return a == b
+}
+
+// applyRestoreChange makes a change; changes of the domain-level addresses
+// restore them from dnsDomain
+func (p *Provider) applyRestoreChange(ctx context.Context, zone string, domainID int64, domain string, change *RestoreChange, dnsDomain *Domain) error {
+	var err error
+	var before, requested, after *libdns.Record
+
+	if change.Before != nil {
+		record := domainRecord(*change.Before, domain)
+		before, requested = &record, &record
+	}
+
+	if id := restoreRecordID(change); isDomainAddressID(id) {
+		// GET and POST /dns/{id}
+		err = p.restoreApexAddress(ctx, domainID, id, *dnsDomain)
+		if change.After != nil {
+			record := domainRecord(*change.After, domain)
+			requested = &record
+			if err == nil {
+				after = &record
+			}
+		}
+	} else if change.Action == SyncDelete {
+		// DELETE /dns/{id}/record/{dnsRecordId}
+		err = p.Client.DeleteRecord(ctx, domainID, fmt.Sprint(change.Before.ID))
+	} else {
+		record := dnsRecordToLibdnsRecord(*change.After, domain)
+		requested = &record
+
+		// POST /dns/{id}/record[/{dnsRecordId}]
+		var updateResponse *DNSRecord
+		updateResponse, err = p.Client.AddOrUpdateRecord(ctx, domainID, writableDNSRecord(*change.After), change.Action == SyncCreate)
+		if err == nil {
+			change.After = updateResponse
+			record := dnsRecordToLibdnsRecord(*updateResponse, domain)
+			after = &record
+		}
+	}
+
+	if auditErr := p.audit(ctx, change.Action.auditOperation(), zone, domainID, before, *requested, after, err); auditErr != nil && err == nil {
+		err = fmt.Errorf("audit: %w", auditErr)
+	}
+	return err
+}
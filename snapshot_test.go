@@ -0,0 +1,112 @@
+package dynu
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotAndRestore(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.",
+		DNSRecord{ID: 1, Type: "A", NodeName: "www", Ipv4Address: "198.51.100.1", TTL: 300, State: true},
+		DNSRecord{ID: 2, Type: "TXT", NodeName: "keep", TextData: "unchanged", TTL: 300, State: true},
+		DNSRecord{ID: 3, Type: "TXT", NodeName: "disabled", TextData: "off", TTL: 300, State: false},
+	)
+	ctx := context.TODO()
+
+	snapshot, err := provider.Snapshot(ctx, "example.com.")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var buffer bytes.Buffer
+	_, err = snapshot.WriteTo(&buffer)
+	if !assert.NoError(t, err) {
+		return
+	}
+	snapshot, err = ReadSnapshot(&buffer)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, SnapshotVersion, snapshot.Version)
+	assert.Equal(t, int64(100), snapshot.DomainID)
+	assert.Len(t, snapshot.Records, 3)
+
+	// risky changes
+	_, err = provider.SetRecords(ctx, "example.com.", []libdns.Record{{ID: "1", Type: "A", Name: "www", Value: "203.0.113.1", TTL: 300 * time.Second}})
+	assert.NoError(t, err)
+	_, err = provider.DeleteRecords(ctx, "example.com.", []libdns.Record{{ID: "3"}})
+	assert.NoError(t, err)
+	_, err = provider.AppendRecords(ctx, "example.com.", []libdns.Record{{Type: "TXT", Name: "new", Value: "added", TTL: 300 * time.Second}})
+	assert.NoError(t, err)
+
+	changes, err := provider.Restore(ctx, "example.com.", snapshot)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	actions := make(map[SyncAction]int)
+	for _, change := range changes {
+		actions[change.Action]++
+	}
+	assert.Equal(t, map[SyncAction]int{SyncUpdate: 1, SyncCreate: 1, SyncDelete: 1}, actions)
+
+	records := fake.Records()
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, "198.51.100.1", records[0].Ipv4Address)
+		assert.Equal(t, "unchanged", records[1].TextData)
+		assert.Equal(t, "off", records[2].TextData)
+		assert.False(t, records[2].State, "state must be restored")
+	}
+
+	// nothing left to do
+	changes, err = provider.Restore(ctx, "example.com.", snapshot)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestReadSnapshotVersion(t *testing.T) {
+	_, err := ReadSnapshot(bytes.NewBufferString(`{"version": 99, "zone": "example.com.", "records": []}`))
+	assert.Error(t, err)
+}
+
+func TestSnapshotKeepsRawRecords(t *testing.T) {
+	raw := `{"id":1,"domainId":100,"nodeName":"www","recordType":"A","ipv4Address":"198.51.100.1","ttl":300,"state":true,"group":"office","updatedOn":"2024-01-02T03:04:05"}`
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns/getroot/example.com":
+			_, _ = w.Write([]byte(`{"statusCode":200,"id":100,"domainName":"example.com","hostname":"example.com"}`))
+		default:
+			_, _ = w.Write([]byte(`{"statusCode":200,"dnsRecords":[` + raw + `]}`))
+		}
+	}))
+	provider := &Provider{APIToken: "token", OwnDomain: "example.com", Client: client}
+
+	snapshot, err := provider.Snapshot(context.TODO(), "example.com.")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var buffer bytes.Buffer
+	_, err = snapshot.WriteTo(&buffer)
+	if !assert.NoError(t, err) {
+		return
+	}
+	snapshot, err = ReadSnapshot(&buffer)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if assert.Len(t, snapshot.Records, 1) {
+		assert.JSONEq(t, raw, string(snapshot.Records[0]))
+	}
+	dnsRecords, err := snapshot.DNSRecords()
+	if assert.NoError(t, err) && assert.Len(t, dnsRecords, 1) {
+		assert.Equal(t, "198.51.100.1", dnsRecords[0].Ipv4Address)
+	}
+}
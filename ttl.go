@@ -0,0 +1,162 @@
+package dynu
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// DefaultTTL is written for records without a TTL unless the TTLPolicy of
+// the Provider sets another default.
+const DefaultTTL = 5 * time.Minute
+
+// TTLMode selects how a TTLPolicy treats TTLs outside its bounds.
+type TTLMode string
+
+const (
+	// TTLClamp rounds TTLs to whole seconds and into the bounds.
+	TTLClamp TTLMode = "clamp"
+	// TTLReject fails records with TTLs outside the bounds or with
+	// fractions of seconds.
+	TTLReject TTLMode = "reject"
+)
+
+// TTLPolicy normalises the TTLs of records written through a Provider, so
+// the TTL read back is the TTL that was written. The zero value writes
+// DefaultTTL for unset TTLs and clamps to MinTTL and MaxTTL.
+type TTLPolicy struct {
+	// Default is written for records with a zero TTL and reported for
+	// records read without one; DefaultTTL if zero.
+	Default time.Duration
+	// Min and Max bound the TTLs written; MinTTL and MaxTTL if zero. They
+	// cannot widen Dynu's bounds, and Min must not exceed Max.
+	Min, Max time.Duration
+	// Mode is TTLClamp if empty.
+	Mode TTLMode
+}
+
+func (p TTLPolicy) bounds() (min, max time.Duration, err error) {
+	min, max = p.Min, p.Max
+	if min < MinTTL {
+		min = MinTTL
+	}
+	if max <= 0 || max > MaxTTL {
+		max = MaxTTL
+	}
+	if min > max {
+		return min, max, fmt.Errorf("TTL policy minimum %s exceeds maximum %s", min, max)
+	}
+	return min, max, nil
+}
+
+func (p TTLPolicy) defaultTTL() time.Duration {
+	if p.Default == 0 {
+		return DefaultTTL
+	}
+	return p.Default
+}
+
+// Apply returns the TTL to write for a requested TTL. In TTLReject mode,
+// the error tells why the TTL is refused. A policy with Min above Max fails
+// in any mode. Negative TTLs are returned as they are, for ValidateRecords
+// to refuse.
+func (p TTLPolicy) Apply(ttl time.Duration) (time.Duration, error) {
+	if ttl < 0 {
+		return ttl, nil
+	}
+	if ttl == 0 {
+		ttl = p.defaultTTL()
+	}
+
+	min, max, err := p.bounds()
+	if err != nil {
+		return ttl, err
+	}
+	if ttl%time.Second != 0 {
+		err = fmt.Errorf("TTL %s is not a whole number of seconds", ttl)
+		ttl = ttl.Round(time.Second)
+	}
+	if ttl < min || ttl > max {
+		err = fmt.Errorf("TTL %s out of range %s to %s", ttl, min, max)
+		if ttl < min {
+			ttl = min
+		} else {
+			ttl = max
+		}
+	}
+
+	if p.Mode == TTLReject {
+		return ttl, err
+	}
+	return ttl, nil
+}
+
+// report returns the TTL to report for a record read from Dynu
+func (p TTLPolicy) report(ttl time.Duration) time.Duration {
+	if ttl == 0 {
+		return p.defaultTTL()
+	}
+	return ttl
+}
+
+// prepareRecords applies the TTL policy to records to be written and
+// validates them against the existing records of the zone and the own domain
+func (p *Provider) prepareRecords(zone string, records, existing []libdns.Record) ([]libdns.Record, error) {
+	return p.prepareRecordsIn(zone, p.ownDomainOf(zoneToFqdn(zone)), records, existing)
+}
+
+// prepareRecordsIn is prepareRecords for records kept in the Dynu domain
+// ownDomain, which need not be the OwnDomain of the Provider
+func (p *Provider) prepareRecordsIn(zone, ownDomain string, records, existing []libdns.Record) ([]libdns.Record, error) {
+	domain := zoneToFqdn(zone)
+	prepared := make([]libdns.Record, len(records))
+	var violations []Violation
+	for i, record := range records {
+		ttl, err := p.TTLPolicy.Apply(record.TTL)
+		if err != nil {
+			violations = append(violations, Violation{Record: record, Reason: err.Error()})
+		}
+		record.TTL = ttl
+		prepared[i] = record
+	}
+
+	var invalid []Violation
+	if validationErr, ok := ValidateRecords(zone, prepared, existing).(*ValidationError); ok {
+		invalid = validationErr.Violations
+	}
+	skip := make(map[libdns.Record]bool, len(invalid))
+	for _, violation := range invalid {
+		skip[violation.Record] = true
+	}
+
+	// whether valid records fit into the own domain, e.g. PTR records
+	for _, record := range prepared {
+		if skip[record] {
+			continue
+		}
+		if _, err := libdnsRecordToDnsRecord(record, domain, ownDomain); err != nil {
+			violations = append(violations, Violation{Record: record, Reason: err.Error()})
+		}
+	}
+	violations = append(violations, invalid...)
+
+	if len(violations) > 0 {
+		return nil, &ValidationError{Violations: violations}
+	}
+	return prepared, nil
+}
+
+// readRecord converts a record read from Dynu, reporting TTLs by the policy
+// and names in Unicode if asked to
+func (p *Provider) readRecord(dnsRecord DNSRecord, domain string) libdns.Record {
+	record := dnsRecordToLibdnsRecord(dnsRecord, domain)
+	record.TTL = p.TTLPolicy.report(record.TTL)
+	if p.UnicodeNames {
+		record.Name = unicodeName(record.Name)
+		if hasHostnameValue(record.Type) {
+			record.Value = unicodeName(record.Value)
+		}
+	}
+	return record
+}
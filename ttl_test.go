@@ -0,0 +1,134 @@
+package dynu
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTLPolicyApply(t *testing.T) {
+	clamp := TTLPolicy{Default: time.Hour, Min: time.Minute, Max: 2 * time.Hour}
+	reject := clamp
+	reject.Mode = TTLReject
+
+	tests := []struct {
+		policy   TTLPolicy
+		ttl      time.Duration
+		expected time.Duration
+		err      string
+	}{
+		{TTLPolicy{}, 0, DefaultTTL, ""},
+		{TTLPolicy{}, time.Second, MinTTL, ""},
+		{TTLPolicy{}, 7 * 24 * time.Hour, MaxTTL, ""},
+		{TTLPolicy{}, 90*time.Second + 400*time.Millisecond, 90 * time.Second, ""},
+		{TTLPolicy{Min: time.Second, Max: 30 * 24 * time.Hour}, time.Second, MinTTL, ""},
+		{TTLPolicy{}, -time.Second, -time.Second, ""},
+		{clamp, 0, time.Hour, ""},
+		{clamp, 30 * time.Second, time.Minute, ""},
+		{clamp, 3 * time.Hour, 2 * time.Hour, ""},
+		{reject, 0, time.Hour, ""},
+		{reject, 5 * time.Minute, 5 * time.Minute, ""},
+		{reject, 30 * time.Second, time.Minute, "TTL 30s out of range 1m0s to 2h0m0s"},
+		{reject, 3 * time.Hour, 2 * time.Hour, "TTL 3h0m0s out of range 1m0s to 2h0m0s"},
+		{reject, 90*time.Second + time.Millisecond, 90 * time.Second, "TTL 1m30.001s is not a whole number of seconds"},
+		{TTLPolicy{Min: 2 * time.Hour, Max: time.Hour}, time.Hour, time.Hour, "TTL policy minimum 2h0m0s exceeds maximum 1h0m0s"},
+		{TTLPolicy{Min: MaxTTL + time.Hour, Mode: TTLReject}, 0, DefaultTTL, "TTL policy minimum 25h0m0s exceeds maximum 24h0m0s"},
+	}
+
+	for _, test := range tests {
+		ttl, err := test.policy.Apply(test.ttl)
+		assert.Equal(t, test.expected, ttl, "%+v %s", test.policy, test.ttl)
+		if test.err == "" {
+			assert.NoError(t, err, "%+v %s", test.policy, test.ttl)
+		} else {
+			assert.EqualError(t, err, test.err)
+		}
+	}
+}
+
+func TestAppendRecordsAppliesTTLPolicy(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.")
+
+	created, err := provider.AppendRecords(context.TODO(), "example.com.", []libdns.Record{
+		{Type: "TXT", Name: "unset", Value: "a"},
+		{Type: "TXT", Name: "short", Value: "b", TTL: 1500 * time.Millisecond},
+		{Type: "TXT", Name: "long", Value: "c", TTL: 30 * 24 * time.Hour},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var ttls []int
+	for _, record := range fake.Records() {
+		ttls = append(ttls, record.TTL)
+	}
+	assert.Equal(t, []int{300, 30, 86400}, ttls)
+
+	if assert.Len(t, created, 3) {
+		assert.Equal(t, DefaultTTL, created[0].TTL)
+		assert.Equal(t, MinTTL, created[1].TTL)
+		assert.Equal(t, MaxTTL, created[2].TTL)
+	}
+}
+
+func TestSetRecordsRejectsTTL(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.",
+		DNSRecord{ID: 1, Type: "TXT", NodeName: "x", TextData: "a", TTL: 300, State: true},
+	)
+	provider.TTLPolicy = TTLPolicy{Max: time.Hour, Mode: TTLReject}
+
+	_, err := provider.SetRecords(context.TODO(), "example.com.", []libdns.Record{
+		{ID: "1", Type: "TXT", Name: "x", Value: "b", TTL: 2 * time.Hour},
+	})
+	assert.EqualError(t, err, `1 invalid records:
+record x TXT "b" (ttl 2h0m0s): TTL 2h0m0s out of range 30s to 1h0m0s`)
+	assert.Equal(t, 300, fake.Records()[0].TTL)
+	assert.Equal(t, "a", fake.Records()[0].TextData)
+}
+
+func TestGetRecordsReportsDefaultTTL(t *testing.T) {
+	provider, _ := newFakeProvider(t, "example.com.",
+		DNSRecord{ID: 1, Type: "TXT", NodeName: "x", TextData: "a", State: true},
+	)
+	provider.TTLPolicy.Default = 10 * time.Minute
+
+	records, err := provider.GetRecords(context.TODO(), "example.com.")
+	if assert.NoError(t, err) && assert.Len(t, records, 1) {
+		assert.Equal(t, 10*time.Minute, records[0].TTL)
+	}
+}
+
+func TestPlanSyncAppliesTTLPolicy(t *testing.T) {
+	provider, _ := newFakeProvider(t, "example.com.")
+
+	spec, err := LoadZoneSpec(strings.NewReader(`
+zone: example.com.
+ttl: 10
+records:
+  - name: www
+    type: A
+    value: 203.0.113.1
+`))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	plan, err := provider.PlanSync(context.TODO(), spec)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "+ www A \"203.0.113.1\" (ttl 30s)\nzone example.com.: 1 to create, 0 to update, 0 to delete\n", plan.String())
+
+	_, err = provider.ApplySync(context.TODO(), plan)
+	assert.NoError(t, err)
+
+	// the clamped TTL must not show up as a change
+	plan, err = provider.PlanSync(context.TODO(), spec)
+	if assert.NoError(t, err) {
+		assert.Empty(t, plan.Changes)
+	}
+}
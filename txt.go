@@ -0,0 +1,65 @@
+package dynu
+
+import "strings"
+
+// encodeTXT returns the textData Dynu expects for a TXT or SPF value. Values
+// that fit into a single character-string are sent as they are, longer ones
+// as quoted character-strings of at most 255 bytes, e.g. DKIM keys:
+//
+//	"v=DKIM1; k=rsa; p=MIIBIjANBgkqh..." "...IDAQAB"
+//
+// Values starting with a quote are always quoted, so they are not mistaken
+// for character-strings on read.
+func encodeTXT(value string) string {
+	if len(value) <= maxCharacterStringLength && !strings.HasPrefix(value, `"`) {
+		return value
+	}
+	return quoteCharacterStrings(value)
+}
+
+// decodeTXT returns the value of textData read from Dynu, joining quoted
+// character-strings. Anything else is returned as it is.
+func decodeTXT(textData string) string {
+	if !strings.HasPrefix(textData, `"`) {
+		return textData
+	}
+	if parts, ok := splitCharacterStrings(textData); ok {
+		return joinCharacterStrings(parts)
+	}
+	return textData
+}
+
+// splitCharacterStrings splits space separated quoted character-strings,
+// keeping their escapes; ok is false unless the whole input is quoted
+func splitCharacterStrings(s string) (parts []string, ok bool) {
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return parts, len(parts) > 0
+		}
+		if s[0] != '"' {
+			return nil, false
+		}
+
+		end := -1
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+				continue
+			}
+			if s[i] == '"' {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return nil, false
+		}
+
+		parts = append(parts, s[1:end])
+		s = s[end+1:]
+		if s != "" && s[0] != ' ' && s[0] != '\t' {
+			return nil, false
+		}
+	}
+}
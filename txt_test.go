@@ -0,0 +1,145 @@
+package dynu
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+// txtValue generates TXT values rich in quotes, escapes and spaces, up to
+// several character-strings long
+type txtValue string
+
+func (txtValue) Generate(rand *rand.Rand, size int) reflect.Value {
+	const alphabet = `ab \"\;=` + "\t\x00\xff" + "é"
+	length := rand.Intn(4 * maxCharacterStringLength)
+	if rand.Intn(4) == 0 {
+		length = rand.Intn(8)
+	}
+
+	var b strings.Builder
+	for b.Len() < length {
+		if rand.Intn(10) == 0 {
+			b.WriteString(`\065`)
+			continue
+		}
+		b.WriteByte(alphabet[rand.Intn(len(alphabet))])
+	}
+	return reflect.ValueOf(txtValue(b.String()))
+}
+
+var quickConfig = &quick.Config{MaxCount: 500}
+
+func TestTXTRoundTrip(t *testing.T) {
+	roundTrip := func(value txtValue) bool {
+		return decodeTXT(encodeTXT(string(value))) == string(value)
+	}
+	if err := quick.Check(roundTrip, quickConfig); err != nil {
+		t.Error(err)
+	}
+
+	// arbitrary strings too
+	if err := quick.Check(func(value string) bool { return decodeTXT(encodeTXT(value)) == value }, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTXTCharacterStringLength(t *testing.T) {
+	fits := func(value txtValue) bool {
+		textData := encodeTXT(string(value))
+		if len(value) <= maxCharacterStringLength && !strings.HasPrefix(string(value), `"`) {
+			return textData == string(value)
+		}
+
+		parts, ok := splitCharacterStrings(textData)
+		if !ok {
+			return false
+		}
+		for _, part := range parts {
+			if length := len(unescapeCharacterString(part)); length == 0 || length > maxCharacterStringLength {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(fits, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTXTZoneFileSyntax(t *testing.T) {
+	// quoted textData must be valid presentation format with the same value
+	parses := func(value txtValue) bool {
+		textData := quoteCharacterStrings(string(value))
+		rr, err := dns.NewRR("example.com. 300 IN TXT " + textData)
+		if err != nil {
+			return false
+		}
+		return joinCharacterStrings(rr.(*dns.TXT).Txt) == string(value)
+	}
+	if err := quick.Check(parses, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTXTRecordConversionRoundTrip(t *testing.T) {
+	roundTrip := func(value txtValue) bool {
+		record := libdns.Record{Type: "TXT", Name: "selector._domainkey", Value: string(value)}
+		dnsRecord, err := libdnsRecordToDnsRecord(record, "example.com", "example.com")
+		if err != nil {
+			return false
+		}
+		dnsRecord.Hostname = "selector._domainkey.example.com"
+		return dnsRecordToLibdnsRecord(dnsRecord, "example.com").Value == string(value)
+	}
+	if err := quick.Check(roundTrip, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncodeTXT(t *testing.T) {
+	assert.Equal(t, "v=spf1 -all", encodeTXT("v=spf1 -all"))
+	assert.Equal(t, `"\"quoted\""`, encodeTXT(`"quoted"`))
+	assert.Equal(t, `back\slash`, encodeTXT(`back\slash`))
+
+	long := strings.Repeat("a", 300)
+	assert.Equal(t, `"`+strings.Repeat("a", 255)+`" "`+strings.Repeat("a", 45)+`"`, encodeTXT(long))
+}
+
+func TestDecodeTXT(t *testing.T) {
+	assert.Equal(t, "v=DKIM1; p=abcdef", decodeTXT(`"v=DKIM1; " "p=abc" "def"`))
+	assert.Equal(t, `say "hi"\`, decodeTXT(`"say \"hi\"\\"`))
+	assert.Equal(t, "A", decodeTXT(`"\065"`))
+	assert.Equal(t, "plain text", decodeTXT("plain text"))
+
+	// not character-strings: returned as they are
+	assert.Equal(t, `"unterminated`, decodeTXT(`"unterminated`))
+	assert.Equal(t, `"a"b`, decodeTXT(`"a"b`))
+	assert.Equal(t, `"a" b`, decodeTXT(`"a" b`))
+}
+
+func TestAppendLongTXTRecord(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.")
+	dkim := "v=DKIM1; k=rsa; p=" + strings.Repeat("MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8A", 12)
+
+	_, err := provider.AppendRecords(context.TODO(), "example.com.", []libdns.Record{
+		{Type: "TXT", Name: "selector._domainkey", Value: dkim, TTL: 5 * time.Minute},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, `"`+dkim[:255]+`" "`+dkim[255:]+`"`, fake.Records()[0].TextData)
+
+	records, err := provider.GetRecords(context.TODO(), "example.com.")
+	if assert.NoError(t, err) && assert.Len(t, records, 1) {
+		assert.Equal(t, dkim, records[0].Value)
+	}
+}
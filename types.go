@@ -1,6 +1,9 @@
 package dynu
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+)
 
 type APIException struct {
 	StatusCode int32  `json:"statusCode,omitempty"`
@@ -38,6 +41,73 @@ type DNSHostname struct {
 	Node       string `json:"node,omitempty"`
 }
 
+// Domain is a domain (DDNS hostname or own domain) in the Dynu account.
+type Domain struct {
+	StatusCode        int32  `json:"statusCode,omitempty"`
+	ID                int64  `json:"id,omitempty"`
+	Name              string `json:"name,omitempty"`
+	UnicodeName       string `json:"unicodeName,omitempty"`
+	Token             string `json:"token,omitempty"`
+	State             string `json:"state,omitempty"`
+	Group             string `json:"group,omitempty"`
+	Ipv4Address       string `json:"ipv4Address,omitempty"`
+	Ipv6Address       string `json:"ipv6Address,omitempty"`
+	TTL               int    `json:"ttl,omitempty"`
+	Ipv4              bool   `json:"ipv4,omitempty"`
+	Ipv6              bool   `json:"ipv6,omitempty"`
+	Ipv4WildcardAlias bool   `json:"ipv4WildcardAlias,omitempty"`
+	Ipv6WildcardAlias bool   `json:"ipv6WildcardAlias,omitempty"`
+	AllowZoneTransfer bool   `json:"allowZoneTransfer,omitempty"`
+	Dnssec            bool   `json:"dnssec,omitempty"`
+	CreatedOn         string `json:"createdOn,omitempty"`
+	UpdatedOn         string `json:"updatedOn,omitempty"`
+}
+
+// DomainRequest holds the settings sent when adding or updating a domain.
+// Dynu replaces all settings on update, so start from Domain.Request.
+type DomainRequest struct {
+	Name              string `json:"name"`
+	Group             string `json:"group,omitempty"`
+	Ipv4Address       string `json:"ipv4Address,omitempty"`
+	Ipv6Address       string `json:"ipv6Address,omitempty"`
+	TTL               int    `json:"ttl,omitempty"`
+	Ipv4              bool   `json:"ipv4"`
+	Ipv6              bool   `json:"ipv6"`
+	Ipv4WildcardAlias bool   `json:"ipv4WildcardAlias"`
+	Ipv6WildcardAlias bool   `json:"ipv6WildcardAlias"`
+	AllowZoneTransfer bool   `json:"allowZoneTransfer"`
+	Dnssec            bool   `json:"dnssec"`
+}
+
+// Request returns the current settings of the domain for UpdateDomain.
+func (d Domain) Request() DomainRequest {
+	return DomainRequest{
+		Name:              d.Name,
+		Group:             d.Group,
+		Ipv4Address:       d.Ipv4Address,
+		Ipv6Address:       d.Ipv6Address,
+		TTL:               d.TTL,
+		Ipv4:              d.Ipv4,
+		Ipv6:              d.Ipv6,
+		Ipv4WildcardAlias: d.Ipv4WildcardAlias,
+		Ipv6WildcardAlias: d.Ipv6WildcardAlias,
+		AllowZoneTransfer: d.AllowZoneTransfer,
+		Dnssec:            d.Dnssec,
+	}
+}
+
+type DomainsResponse struct {
+	StatusCode int32    `json:"statusCode,omitempty"`
+	Domains    []Domain `json:"domains,omitempty"`
+}
+
+// RawRecordsResponse is a RecordsResponse with the records kept as the JSON
+// returned by Dynu, including fields DNSRecord does not model.
+type RawRecordsResponse struct {
+	StatusCode int32             `json:"statusCode,omitempty"`
+	DNSRecords []json.RawMessage `json:"dnsRecords,omitempty"`
+}
+
 type RecordsResponse struct {
 	StatusCode int32       `json:"statusCode,omitempty"`
 	DNSRecords []DNSRecord `json:"dnsRecords,omitempty"`
@@ -0,0 +1,245 @@
+package dynu
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// TTL bounds accepted by Dynu. A zero TTL leaves the choice to Dynu.
+const (
+	MinTTL = 30 * time.Second
+	MaxTTL = 24 * time.Hour
+)
+
+const maxMXPriority = 65535
+
+// Violation is a record that Dynu would reject or store differently than
+// requested.
+type Violation struct {
+	Record libdns.Record
+	Reason string
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("record %s: %s", formatSyncRecord(v.Record), v.Reason)
+}
+
+// ValidationError lists all violations found in a set of records.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		lines[i] = violation.Error()
+	}
+	return fmt.Sprintf("%d invalid records:\n%s", len(e.Violations), strings.Join(lines, "\n"))
+}
+
+// ValidateRecords checks records before they are written to the zone: the
+// syntax of names, addresses and targets per record type, TTL bounds, MX
+// priorities and that a CNAME is the only record at its name, among the
+// records and the existing records of the zone. Existing records with the
+// ID of one of the records are replaced by it. All violations are returned
+// at once as a *ValidationError.
+func ValidateRecords(zone string, records, existing []libdns.Record) error {
+	domain := zoneToFqdn(zone)
+
+	var violations []Violation
+	for _, record := range records {
+		for _, reason := range validateRecord(record, domain) {
+			violations = append(violations, Violation{Record: record, Reason: reason})
+		}
+	}
+	violations = append(violations, validateCNAMEs(domain, records, existing)...)
+
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+func validateRecord(record libdns.Record, domain string) []string {
+	var reasons []string
+	invalid := func(format string, args ...any) {
+		reasons = append(reasons, fmt.Sprintf(format, args...))
+	}
+
+	name := record.Name
+	if name == "@" {
+		name = ""
+	}
+	if fqdn, err := toASCII(libdns.AbsoluteName(name, domain)); err != nil {
+		invalid("invalid name: %v", err)
+	} else if err := checkHostname(fqdn, true); err != nil {
+		invalid("invalid name: %v", err)
+	}
+
+	switch {
+	case record.TTL < 0:
+		invalid("negative TTL")
+	case record.TTL%time.Second != 0:
+		invalid("TTL %s is not a whole number of seconds", record.TTL)
+	case record.TTL != 0 && (record.TTL < MinTTL || record.TTL > MaxTTL):
+		invalid("TTL %s out of range %s to %s", record.TTL, MinTTL, MaxTTL)
+	}
+
+	switch record.Type {
+	case "A":
+		if addr, err := netip.ParseAddr(record.Value); err != nil || !addr.Is4() {
+			invalid("invalid IPv4 address")
+		}
+	case "AAAA":
+		if addr, err := netip.ParseAddr(record.Value); err != nil || !addr.Is6() || addr.Is4In6() || addr.Zone() != "" {
+			invalid("invalid IPv6 address")
+		}
+	case "CNAME":
+		if normalizeName(record.Name, domain) == "@" {
+			invalid("CNAME not allowed at the zone apex")
+		}
+		fallthrough
+	case "NS":
+		if err := checkTarget(record.Value); err != nil {
+			invalid("invalid target: %v", err)
+		}
+	case "PTR":
+		if _, err := ptrReverseName(record.Name, domain); err != nil {
+			invalid("%v", err)
+		}
+		if err := checkTarget(record.Value); err != nil {
+			invalid("invalid target: %v", err)
+		}
+	case "MX":
+		if err := checkTarget(record.Value); err != nil {
+			invalid("invalid target: %v", err)
+		}
+		if record.Priority > maxMXPriority {
+			invalid("priority %d above %d", record.Priority, maxMXPriority)
+		}
+	case "SPF", "TXT":
+	default:
+		invalid("record type not supported")
+	}
+
+	return reasons
+}
+
+// checkHostname checks the syntax of a domain name; underscores are allowed
+// for service names like _acme-challenge, a leading * label only if wildcard
+func checkHostname(hostname string, wildcard bool) error {
+	hostname = strings.TrimSuffix(hostname, ".")
+	if hostname == "" {
+		return fmt.Errorf("empty hostname")
+	}
+	if len(hostname) > 253 {
+		return fmt.Errorf("%q longer than 253 characters", hostname)
+	}
+
+	for i, label := range strings.Split(hostname, ".") {
+		switch {
+		case label == "":
+			return fmt.Errorf("%q has an empty label", hostname)
+		case label == "*" && i == 0 && wildcard:
+			continue
+		case len(label) > 63:
+			return fmt.Errorf("%q has a label longer than 63 characters", hostname)
+		case label[0] == '-' || label[len(label)-1] == '-':
+			return fmt.Errorf("%q has a label starting or ending with a hyphen", hostname)
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return fmt.Errorf("%q contains invalid character %q", hostname, c)
+			}
+		}
+	}
+	return nil
+}
+
+// checkTarget checks the hostname a record points to
+func checkTarget(hostname string) error {
+	ascii, err := toASCII(hostname)
+	if err != nil {
+		return err
+	}
+	return checkHostname(ascii, false)
+}
+
+// writesCNAME reports whether records include a CNAME; only then are records
+// written by the Provider checked against the existing records of the zone
+func writesCNAME(records []libdns.Record) bool {
+	for _, record := range records {
+		if record.Type == "CNAME" {
+			return true
+		}
+	}
+	return false
+}
+
+// validateCNAMEs checks that every CNAME among records is alone at its name
+// once records are written next to the existing ones
+func validateCNAMEs(domain string, records, existing []libdns.Record) []Violation {
+	replaced := make(map[string]bool)
+	for _, record := range records {
+		if record.ID != "" {
+			replaced[record.ID] = true
+		}
+	}
+
+	// types at each name, per record of the resulting zone
+	type entry struct {
+		recordType string
+		requested  bool
+	}
+	byName := make(map[string][]entry)
+	for _, record := range existing {
+		if !replaced[record.ID] {
+			name := normalizeName(record.Name, domain)
+			byName[name] = append(byName[name], entry{record.Type, false})
+		}
+	}
+	for _, record := range records {
+		name := normalizeName(record.Name, domain)
+		byName[name] = append(byName[name], entry{record.Type, true})
+	}
+
+	var violations []Violation
+	for _, record := range records {
+		entries := byName[normalizeName(record.Name, domain)]
+		if len(entries) < 2 {
+			continue
+		}
+
+		if record.Type == "CNAME" {
+			others := make(map[string]bool)
+			skippedSelf := false
+			for _, entry := range entries {
+				if entry.requested && entry.recordType == "CNAME" && !skippedSelf {
+					skippedSelf = true
+					continue
+				}
+				others[entry.recordType] = true
+			}
+			var types []string
+			for recordType := range others {
+				types = append(types, recordType)
+			}
+			sort.Strings(types)
+			violations = append(violations, Violation{Record: record, Reason: "CNAME must be the only record at its name, found " + strings.Join(types, ", ")})
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.recordType == "CNAME" {
+				violations = append(violations, Violation{Record: record, Reason: "name has a CNAME record"})
+				break
+			}
+		}
+	}
+	return violations
+}
@@ -0,0 +1,166 @@
+package dynu
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRecords(t *testing.T) {
+	tests := []struct {
+		name   string
+		record libdns.Record
+		reason string
+	}{
+		{"A", libdns.Record{Type: "A", Name: "www", Value: "203.0.113.1", TTL: time.Minute}, ""},
+		{"A malformed", libdns.Record{Type: "A", Name: "www", Value: "203.0.113"}, "invalid IPv4 address"},
+		{"A with IPv6", libdns.Record{Type: "A", Name: "www", Value: "2001:db8::1"}, "invalid IPv4 address"},
+		{"AAAA", libdns.Record{Type: "AAAA", Name: "www", Value: "2001:db8::1"}, ""},
+		{"AAAA with IPv4", libdns.Record{Type: "AAAA", Name: "www", Value: "203.0.113.1"}, "invalid IPv6 address"},
+		{"AAAA IPv4-mapped", libdns.Record{Type: "AAAA", Name: "www", Value: "::ffff:203.0.113.1"}, "invalid IPv6 address"},
+		{"AAAA with zone", libdns.Record{Type: "AAAA", Name: "www", Value: "fe80::1%eth0"}, "invalid IPv6 address"},
+		{"CNAME", libdns.Record{Type: "CNAME", Name: "www", Value: "example.net."}, ""},
+		{"CNAME at apex", libdns.Record{Type: "CNAME", Name: "@", Value: "example.net"}, "CNAME not allowed at the zone apex"},
+		{"CNAME invalid target", libdns.Record{Type: "CNAME", Name: "www", Value: "exa mple.net"}, `invalid target: "exa mple.net" contains invalid character ' '`},
+		{"MX", libdns.Record{Type: "MX", Name: "@", Value: "mail.example.com", Priority: 65535}, ""},
+		{"MX priority", libdns.Record{Type: "MX", Name: "@", Value: "mail.example.com", Priority: 65536}, "priority 65536 above 65535"},
+		{"NS empty target", libdns.Record{Type: "NS", Name: "sub", Value: ""}, "invalid target: empty hostname"},
+		{"TXT service name", libdns.Record{Type: "TXT", Name: "_acme-challenge.www", Value: "token"}, ""},
+		{"wildcard", libdns.Record{Type: "TXT", Name: "*.dev", Value: "token"}, ""},
+		{"wildcard not leftmost", libdns.Record{Type: "TXT", Name: "dev.*", Value: "token"}, `invalid name: "dev.*.example.com" contains invalid character '*'`},
+		{"empty label", libdns.Record{Type: "TXT", Name: "a..b", Value: "x"}, `invalid name: "a..b.example.com" has an empty label`},
+		{"hyphen", libdns.Record{Type: "TXT", Name: "-a", Value: "x"}, `invalid name: "-a.example.com" has a label starting or ending with a hyphen`},
+		{"long label", libdns.Record{Type: "TXT", Name: strings.Repeat("a", 64), Value: "x"}, "has a label longer than 63 characters"},
+		{"negative TTL", libdns.Record{Type: "TXT", Name: "x", Value: "x", TTL: -time.Second}, "negative TTL"},
+		{"sub-second TTL", libdns.Record{Type: "TXT", Name: "x", Value: "x", TTL: 1500 * time.Millisecond}, "TTL 1.5s is not a whole number of seconds"},
+		{"TTL too low", libdns.Record{Type: "TXT", Name: "x", Value: "x", TTL: time.Second}, "TTL 1s out of range 30s to 24h0m0s"},
+		{"TTL too high", libdns.Record{Type: "TXT", Name: "x", Value: "x", TTL: 48 * time.Hour}, "TTL 48h0m0s out of range 30s to 24h0m0s"},
+		{"unsupported type", libdns.Record{Type: "SRV", Name: "_sip._tcp", Value: "0 5 5060 sip.example.com"}, "record type not supported"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateRecords("example.com.", []libdns.Record{test.record}, nil)
+			if test.reason == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var validationErr *ValidationError
+			if assert.ErrorAs(t, err, &validationErr) && assert.Len(t, validationErr.Violations, 1) {
+				assert.Contains(t, validationErr.Violations[0].Reason, test.reason)
+				assert.Equal(t, test.record, validationErr.Violations[0].Record)
+			}
+		})
+	}
+}
+
+func TestValidateRecordsAllViolations(t *testing.T) {
+	err := ValidateRecords("example.com.", []libdns.Record{
+		{Type: "A", Name: "www", Value: "300.0.0.1", TTL: -time.Second},
+		{Type: "TXT", Name: "ok", Value: "fine"},
+		{Type: "MX", Name: "@", Value: "mail.example.com", Priority: 70000},
+	}, nil)
+
+	assert.EqualError(t, err, `3 invalid records:
+record www A "300.0.0.1" (ttl -1s): negative TTL
+record www A "300.0.0.1" (ttl -1s): invalid IPv4 address
+record @ MX 70000 "mail.example.com" (ttl 0s): priority 70000 above 65535`)
+}
+
+func TestValidateRecordsCNAMEExclusivity(t *testing.T) {
+	existing := []libdns.Record{
+		{ID: "1", Type: "A", Name: "www", Value: "203.0.113.1"},
+		{ID: "2", Type: "CNAME", Name: "alias", Value: "example.net"},
+		{ID: "3", Type: "TXT", Name: "www", Value: "hello"},
+	}
+
+	reasons := func(records []libdns.Record, existing []libdns.Record) []string {
+		var validationErr *ValidationError
+		if err := ValidateRecords("example.com.", records, existing); !errors.As(err, &validationErr) {
+			return nil
+		}
+		var reasons []string
+		for _, violation := range validationErr.Violations {
+			reasons = append(reasons, violation.Record.Name+" "+violation.Record.Type+": "+violation.Reason)
+		}
+		return reasons
+	}
+
+	assert.Equal(t, []string{"www CNAME: CNAME must be the only record at its name, found A, TXT"},
+		reasons([]libdns.Record{{Type: "CNAME", Name: "www", Value: "example.net"}}, existing))
+	assert.Equal(t, []string{"alias A: name has a CNAME record"},
+		reasons([]libdns.Record{{Type: "A", Name: "alias", Value: "203.0.113.1"}}, existing))
+	assert.Equal(t, []string{
+		"new CNAME: CNAME must be the only record at its name, found CNAME",
+		"NEW.example.com. CNAME: CNAME must be the only record at its name, found CNAME",
+	}, reasons([]libdns.Record{{Type: "CNAME", Name: "new", Value: "a.example.net"}, {Type: "CNAME", Name: "NEW.example.com.", Value: "b.example.net"}}, nil))
+
+	// replacing the CNAME by ID frees the name
+	assert.Nil(t, reasons([]libdns.Record{{ID: "2", Type: "A", Name: "alias", Value: "203.0.113.1"}}, existing))
+	assert.Nil(t, reasons([]libdns.Record{{Type: "CNAME", Name: "other", Value: "example.net"}}, existing))
+}
+
+func TestAppendRecordsValidatesBeforeWriting(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.",
+		DNSRecord{ID: 1, Type: "A", NodeName: "www", Ipv4Address: "203.0.113.1", TTL: 300, State: true},
+	)
+
+	created, err := provider.AppendRecords(context.TODO(), "example.com.", []libdns.Record{
+		{Type: "TXT", Name: "fine", Value: "ok"},
+		{Type: "CNAME", Name: "www", Value: "example.net"},
+		{Type: "AAAA", Name: "v6", Value: "203.0.113.1"},
+	})
+
+	var validationErr *ValidationError
+	if assert.ErrorAs(t, err, &validationErr) {
+		assert.Len(t, validationErr.Violations, 2)
+	}
+	assert.Empty(t, created)
+	assert.Equal(t, []string{"GET /dns/getroot/example.com", "GET /dns/100/record"}, fake.Requests())
+	assert.Len(t, fake.Records(), 1)
+}
+
+func TestSetRecordsReplacesByID(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.",
+		DNSRecord{ID: 1, Type: "A", NodeName: "www", Ipv4Address: "203.0.113.1", TTL: 300, State: true},
+	)
+
+	_, err := provider.SetRecords(context.TODO(), "example.com.", []libdns.Record{
+		{ID: "1", Type: "CNAME", Name: "www", Value: "example.net", TTL: 5 * time.Minute},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "CNAME", fake.Records()[0].Type)
+
+	// appending never replaces, even with an ID
+	_, err = provider.AppendRecords(context.TODO(), "example.com.", []libdns.Record{
+		{ID: "1", Type: "CNAME", Name: "www", Value: "example.org"},
+	})
+	assert.ErrorContains(t, err, "CNAME must be the only record at its name, found CNAME")
+}
+
+func TestApplyBatchValidates(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.",
+		DNSRecord{ID: 1, Type: "A", NodeName: "www", Ipv4Address: "203.0.113.1", TTL: 300, State: true},
+	)
+
+	_, err := provider.ApplyBatch(context.TODO(), "example.com.", Batch{
+		Create: []libdns.Record{{Type: "CNAME", Name: "www", Value: "example.net"}},
+	})
+	assert.ErrorContains(t, err, "CNAME must be the only record at its name, found A")
+
+	// deleting the A record in the same batch makes room for the CNAME
+	_, err = provider.ApplyBatch(context.TODO(), "example.com.", Batch{
+		Create: []libdns.Record{{Type: "CNAME", Name: "www", Value: "example.net", TTL: time.Minute}},
+		Delete: []libdns.Record{{ID: "1"}},
+	})
+	assert.NoError(t, err)
+	if records := fake.Records(); assert.Len(t, records, 1) {
+		assert.Equal(t, "CNAME", records[0].Type)
+	}
+}
@@ -0,0 +1,65 @@
+package dynu
+
+import (
+	"context"
+	"strings"
+)
+
+// isWildcard reports whether a name is a wildcard, i.e. its leftmost label
+// is *. Dynu keeps wildcard records on the node "*" or "*.sub".
+func isWildcard(name string) bool {
+	return name == "*" || strings.HasPrefix(name, "*.")
+}
+
+// WildcardAlias are the domain-level wildcard flags of a Dynu domain. When
+// set, names below the domain without records of their own resolve to the
+// IPv4 or IPv6 address of the domain, like a "*" A or AAAA record would.
+type WildcardAlias struct {
+	IPv4 bool
+	IPv6 bool
+}
+
+// GetWildcardAlias returns the wildcard flags of the Dynu domain of the
+// zone.
+func (p *Provider) GetWildcardAlias(ctx context.Context, zone string) (WildcardAlias, error) {
+	p.Once.Do(func() { p.init() })
+
+	domain, err := p.dnsDomain(ctx, zone)
+	if err != nil {
+		return WildcardAlias{}, err
+	}
+	return WildcardAlias{IPv4: domain.Ipv4WildcardAlias, IPv6: domain.Ipv6WildcardAlias}, nil
+}
+
+// SetWildcardAlias sets the wildcard flags of the Dynu domain of the zone,
+// keeping its other settings.
+func (p *Provider) SetWildcardAlias(ctx context.Context, zone string, alias WildcardAlias) error {
+	p.Once.Do(func() { p.init() })
+
+	domain, err := p.dnsDomain(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	request := domain.Request()
+	request.Ipv4WildcardAlias = alias.IPv4
+	request.Ipv6WildcardAlias = alias.IPv6
+	if request == domain.Request() {
+		return nil
+	}
+
+	// POST /dns/{id}
+	return p.Client.UpdateDomain(ctx, domain.ID, request)
+}
+
+// dnsDomain returns the Dynu domain of the zone
+func (p *Provider) dnsDomain(ctx context.Context, zone string) (*Domain, error) {
+	// GET /dns/getroot/{hostname}
+	root, err := p.Client.GetRootDomain(ctx, p.ownDomainOf(zone))
+	if err != nil {
+		return nil, err
+	}
+
+	// GET /dns/{id}
+	return p.Client.GetDomain(ctx, root.ID)
+}
@@ -0,0 +1,92 @@
+package dynu
+
+import (
+	"context"
+	"testing"
+
+	"github.com/libdns/libdns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWildcardRecordNames(t *testing.T) {
+	tests := []struct {
+		zone, ownDomain, name, nodeName string
+	}{
+		{"example.com", "example.com", "*", "*"},
+		{"example.com", "example.com", "*.sub", "*.sub"},
+		{"dynu.com", "my.dynu.com", "*.my", "*"},
+		{"dynu.com", "my.dynu.com", "*.sub.my", "*.sub"},
+	}
+
+	for _, test := range tests {
+		record := libdns.Record{ID: "42", Type: "A", Name: test.name, Value: "192.0.2.1"}
+		dnsRecord, err := libdnsRecordToDnsRecord(record, test.zone, test.ownDomain)
+		if assert.NoError(t, err, test.name) {
+			assert.Equal(t, test.nodeName, dnsRecord.NodeName, test.name)
+		}
+
+		dnsRecord.Hostname = test.nodeName + "." + test.ownDomain
+		assert.Equal(t, record, dnsRecordToLibdnsRecord(dnsRecord, test.zone), test.name)
+	}
+}
+
+func TestWildcardRecordOutsideOwnDomain(t *testing.T) {
+	_, err := libdnsRecordToDnsRecord(libdns.Record{Type: "A", Name: "*", Value: "192.0.2.1"}, "dynu.com", "my.dynu.com")
+	assert.EqualError(t, err, `wildcard name "*" must be below my.dynu.com, the domain in Dynu`)
+
+	_, err = libdnsRecordToDnsRecord(libdns.Record{Type: "A", Name: "www", Value: "192.0.2.1"}, "dynu.com", "my.dynu.com")
+	assert.EqualError(t, err, `record name "www": "www.dynu.com" is not in my.dynu.com, the domain in Dynu`)
+}
+
+func TestAppendWildcardRecords(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.")
+
+	records, err := provider.AppendRecords(context.TODO(), "example.com.", []libdns.Record{
+		{Type: "A", Name: "*", Value: "192.0.2.1"},
+		{Type: "TXT", Name: "*.sub", Value: "wildcard"},
+	})
+	if assert.NoError(t, err) && assert.Len(t, records, 2) {
+		assert.Equal(t, "*", records[0].Name)
+		assert.Equal(t, "*.sub", records[1].Name)
+	}
+
+	stored := fake.Records()
+	if assert.Len(t, stored, 2) {
+		assert.Equal(t, "*.example.com", stored[0].Hostname)
+		assert.Equal(t, "*.sub.example.com", stored[1].Hostname)
+	}
+
+	_, err = provider.AppendRecords(context.TODO(), "example.com.", []libdns.Record{
+		{Type: "A", Name: "sub.*", Value: "192.0.2.1"},
+	})
+	assert.Error(t, err)
+	assert.Len(t, fake.Records(), 2)
+}
+
+func TestSetWildcardAlias(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.")
+
+	alias, err := provider.GetWildcardAlias(context.TODO(), "example.com.")
+	if assert.NoError(t, err) {
+		assert.Equal(t, WildcardAlias{}, alias)
+	}
+
+	err = provider.SetWildcardAlias(context.TODO(), "example.com.", WildcardAlias{IPv4: true})
+	if assert.NoError(t, err) {
+		domain := fake.Domains()[0]
+		assert.True(t, domain.Ipv4WildcardAlias)
+		assert.False(t, domain.Ipv6WildcardAlias)
+		assert.Equal(t, "example.com", domain.Name)
+		assert.Equal(t, 90, domain.TTL)
+	}
+
+	alias, err = provider.GetWildcardAlias(context.TODO(), "example.com.")
+	if assert.NoError(t, err) {
+		assert.Equal(t, WildcardAlias{IPv4: true}, alias)
+	}
+
+	// unchanged flags are not sent again
+	requests := len(fake.Requests())
+	assert.NoError(t, provider.SetWildcardAlias(context.TODO(), "example.com.", WildcardAlias{IPv4: true}))
+	assert.NotContains(t, fake.Requests()[requests:], "POST /dns/100")
+}
@@ -0,0 +1,342 @@
+package dynu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+	"gopkg.in/yaml.v3"
+)
+
+// ZoneSpec is the desired state of a zone, usually kept in a YAML or JSON
+// file under version control.
+type ZoneSpec struct {
+	Zone string `json:"zone" yaml:"zone"`
+	// TTL in seconds for records without one.
+	TTL int `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	// Managed limits the sync to records whose name matches one of these
+	// path.Match patterns, e.g. "www" or "_acme-*"; other records are left
+	// alone. All records are managed if empty.
+	Managed []string     `json:"managed,omitempty" yaml:"managed,omitempty"`
+	Records []SpecRecord `json:"records" yaml:"records"`
+}
+
+// SpecRecord is a record in a ZoneSpec, named relative to the zone.
+type SpecRecord struct {
+	Name     string `json:"name" yaml:"name"`
+	Type     string `json:"type" yaml:"type"`
+	Value    string `json:"value" yaml:"value"`
+	TTL      int    `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	Priority uint   `json:"priority,omitempty" yaml:"priority,omitempty"`
+}
+
+// LoadZoneSpec reads a ZoneSpec in YAML or JSON format.
+func LoadZoneSpec(r io.Reader) (*ZoneSpec, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	spec := &ZoneSpec{}
+	if err := decoder.Decode(spec); err != nil {
+		return nil, fmt.Errorf("zone spec: %w", err)
+	}
+	if spec.Zone == "" {
+		return nil, errors.New("zone spec: zone is required")
+	}
+	for i, record := range spec.Records {
+		if record.Type == "" || record.Value == "" {
+			return nil, fmt.Errorf("zone spec: record %d: type and value are required", i+1)
+		}
+	}
+	for _, pattern := range spec.Managed {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("zone spec: managed pattern %q: %w", pattern, err)
+		}
+	}
+	return spec, nil
+}
+
+// LibdnsRecords returns the records of the spec with defaults applied and
+// names relative to the zone.
+func (s *ZoneSpec) LibdnsRecords() []libdns.Record {
+	records := make([]libdns.Record, 0, len(s.Records))
+	for _, record := range s.Records {
+		name := record.Name
+		if name == "" {
+			name = "@"
+		}
+		ttl := record.TTL
+		if ttl == 0 {
+			ttl = s.TTL
+		}
+		// same names and values as DiffRecords compares them
+		records = append(records, normalizeRecord(libdns.Record{
+			Type:     record.Type,
+			Name:     name,
+			Value:    record.Value,
+			TTL:      time.Duration(ttl) * time.Second,
+			Priority: record.Priority,
+		}, zoneToFqdn(s.Zone)))
+	}
+	return records
+}
+
+func (s *ZoneSpec) manages(name string) bool {
+	if len(s.Managed) == 0 {
+		return true
+	}
+	for _, pattern := range s.Managed {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// SyncAction is the kind of a SyncChange.
+type SyncAction string
+
+const (
+	SyncCreate SyncAction = "create"
+	SyncUpdate SyncAction = "update"
+	SyncDelete SyncAction = "delete"
+)
+
+// auditOperation returns the AuditEvent operation of the action
+func (a SyncAction) auditOperation() string {
+	switch a {
+	case SyncCreate:
+		return "append"
+	case SyncDelete:
+		return "delete"
+	default:
+		return "set"
+	}
+}
+
+// SyncChange is a single change of a SyncPlan. Before is nil for creations,
+// After for deletions.
+type SyncChange struct {
+	Action SyncAction
+	Before *libdns.Record
+	After  *libdns.Record
+}
+
+func (c SyncChange) String() string {
+	switch c.Action {
+	case SyncCreate:
+		return "+ " + formatSyncRecord(*c.After)
+	case SyncDelete:
+		return "- " + formatSyncRecord(*c.Before)
+	default:
+		return "~ " + formatSyncRecord(*c.Before) + " => " + formatSyncValue(*c.After)
+	}
+}
+
+func formatSyncRecord(record libdns.Record) string {
+	return record.Name + " " + record.Type + " " + formatSyncValue(record)
+}
+
+func formatSyncValue(record libdns.Record) string {
+	value := strconv.Quote(record.Value)
+	if record.Type == "MX" {
+		value = fmt.Sprintf("%d %s", record.Priority, value)
+	}
+	return fmt.Sprintf("%s (ttl %s)", value, record.TTL)
+}
+
+// SyncPlan lists the changes needed to bring a zone to its ZoneSpec.
+type SyncPlan struct {
+	Zone    string
+	Changes []SyncChange
+}
+
+// String returns the plan in a human-readable form, one change per line.
+func (p *SyncPlan) String() string {
+	if len(p.Changes) == 0 {
+		return fmt.Sprintf("zone %s is up to date\n", p.Zone)
+	}
+
+	var b strings.Builder
+	counts := make(map[SyncAction]int)
+	for _, change := range p.Changes {
+		counts[change.Action]++
+		b.WriteString(change.String())
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "zone %s: %d to create, %d to update, %d to delete\n", p.Zone, counts[SyncCreate], counts[SyncUpdate], counts[SyncDelete])
+	return b.String()
+}
+
+// PlanSync compares the zone with the spec. Records are updated in place
+// where the name and type match, which keeps their IDs.
+func (p *Provider) PlanSync(ctx context.Context, spec *ZoneSpec) (*SyncPlan, error) {
+	current, err := p.GetRecords(ctx, spec.Zone)
+	if err != nil {
+		return nil, err
+	}
+
+	var managed []libdns.Record
+	byID := make(map[string]libdns.Record)
+	for _, record := range current {
+		if spec.manages(record.Name) {
+			managed = append(managed, record)
+			byID[record.ID] = record
+		}
+	}
+
+	var desired []libdns.Record
+	desiredKeys := make(map[rrsetKey]bool)
+	for _, record := range spec.LibdnsRecords() {
+		if !spec.manages(record.Name) {
+			return nil, fmt.Errorf("zone spec: record %s %s is outside the managed names", record.Name, record.Type)
+		}
+		desired = append(desired, record)
+		desiredKeys[rrsetKey{record.Name, record.Type}] = true
+	}
+
+	// with the TTL policy applied, so the plan compares what would be written
+	desired, err = p.prepareRecords(spec.Zone, desired, nil)
+	if err != nil {
+		return nil, fmt.Errorf("zone spec: %w", err)
+	}
+
+	plan := &SyncPlan{Zone: spec.Zone}
+
+	create, update, remove := planRRsets(managed, desired)
+	for i := range update {
+		before := byID[update[i].ID]
+		plan.Changes = append(plan.Changes, SyncChange{Action: SyncUpdate, Before: &before, After: &update[i]})
+	}
+	for i := range create {
+		plan.Changes = append(plan.Changes, SyncChange{Action: SyncCreate, After: &create[i]})
+	}
+	for i := range remove {
+		plan.Changes = append(plan.Changes, SyncChange{Action: SyncDelete, Before: &remove[i]})
+	}
+
+	// planRRsets only looks at names and types in the spec
+	for i := range managed {
+		if !desiredKeys[rrsetKey{managed[i].Name, managed[i].Type}] {
+			plan.Changes = append(plan.Changes, SyncChange{Action: SyncDelete, Before: &managed[i]})
+		}
+	}
+
+	// check the zone as it will be after the sync
+	var writes []libdns.Record
+	deleted := make(map[string]bool)
+	for _, change := range plan.Changes {
+		if change.Action == SyncDelete {
+			deleted[change.Before.ID] = true
+		} else {
+			writes = append(writes, *change.After)
+		}
+	}
+	var kept []libdns.Record
+	for _, record := range current {
+		if !deleted[record.ID] {
+			kept = append(kept, record)
+		}
+	}
+	if err := ValidateRecords(spec.Zone, writes, kept); err != nil {
+		return nil, fmt.Errorf("zone spec: %w", err)
+	}
+
+	return plan, nil
+}
+
+// SyncFailure is a change that could not be applied.
+type SyncFailure struct {
+	Change SyncChange
+	Err    error
+}
+
+// SyncResult reports which changes of a plan were applied.
+type SyncResult struct {
+	Applied []SyncChange
+	Failed  []SyncFailure
+}
+
+// ApplySync makes the changes of the plan through the Client: updates first,
+// then deletions, so a CNAME can replace other records, then creations. It
+// continues after failures and reports them in the result and the error.
+func (p *Provider) ApplySync(ctx context.Context, plan *SyncPlan) (*SyncResult, error) {
+	p.Once.Do(func() { p.init() })
+
+	result := &SyncResult{}
+	domain := zoneToFqdn(plan.Zone)
+
+	// GET /dns/getroot/{hostname}
+	dnsHostName, err := p.Client.GetRootDomain(ctx, p.ownDomainOf(plan.Zone))
+	if err != nil {
+		return result, err
+	}
+
+	var errs []error
+	for _, action := range []SyncAction{SyncUpdate, SyncDelete, SyncCreate} {
+		for _, change := range plan.Changes {
+			if change.Action != action {
+				continue
+			}
+
+			after, err := p.applySyncChange(ctx, dnsHostName.ID, domain, change)
+
+			var requested libdns.Record
+			if change.After != nil {
+				requested = *change.After
+			} else {
+				requested = *change.Before
+			}
+			if auditErr := p.audit(ctx, change.Action.auditOperation(), plan.Zone, dnsHostName.ID, change.Before, requested, after, err); auditErr != nil {
+				errs = append(errs, fmt.Errorf("audit: %w", auditErr))
+			}
+
+			if err != nil {
+				err = fmt.Errorf("%s: %w", change, err)
+				errs = append(errs, err)
+				result.Failed = append(result.Failed, SyncFailure{Change: change, Err: err})
+				continue
+			}
+			result.Applied = append(result.Applied, change)
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+func (p *Provider) applySyncChange(ctx context.Context, domainID int64, domain string, change SyncChange) (*libdns.Record, error) {
+	if change.Action == SyncDelete {
+		if p.ApexAddresses && isDomainAddressID(change.Before.ID) {
+			// GET and POST /dns/{id}
+			return nil, p.deleteApexAddress(ctx, domainID, change.Before.ID)
+		}
+
+		// DELETE /dns/{id}/record/{dnsRecordId}
+		return nil, p.Client.DeleteRecord(ctx, domainID, change.Before.ID)
+	}
+
+	dnsRecord, err := libdnsRecordToDnsRecord(*change.After, domain, p.ownDomainOf(domain))
+	if err != nil {
+		return nil, err
+	}
+
+	if p.isApexAddress(*change.After, dnsRecord) {
+		// GET and POST /dns/{id}
+		// the TTL of the plan is the one of the zone spec
+		return p.writeApexAddress(ctx, domainID, domain, *change.After, change.Action == SyncCreate, true)
+	}
+
+	// POST /dns/{id}/record[/{dnsRecordId}]
+	updateResponse, err := p.Client.AddOrUpdateRecord(ctx, domainID, dnsRecord, change.Action == SyncCreate)
+	if err != nil {
+		return nil, err
+	}
+
+	after := dnsRecordToLibdnsRecord(*updateResponse, domain)
+	return &after, nil
+}
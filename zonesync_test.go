@@ -0,0 +1,112 @@
+package dynu
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/stretchr/testify/assert"
+)
+
+const testZoneSpec = `
+zone: example.com.
+ttl: 300
+managed: ["@", "www", "api"]
+records:
+  - name: "@"
+    type: A
+    value: 203.0.113.1
+  - name: www
+    type: cname
+    value: example.com
+  - name: api
+    type: AAAA
+    value: 2001:db8::1
+    ttl: 60
+`
+
+func TestLoadZoneSpec(t *testing.T) {
+	spec, err := LoadZoneSpec(strings.NewReader(testZoneSpec))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "example.com.", spec.Zone)
+	assert.Equal(t, []libdns.Record{
+		{Type: "A", Name: "@", Value: "203.0.113.1", TTL: 300 * time.Second},
+		{Type: "CNAME", Name: "www", Value: "example.com", TTL: 300 * time.Second},
+		{Type: "AAAA", Name: "api", Value: "2001:db8::1", TTL: 60 * time.Second},
+	}, spec.LibdnsRecords())
+
+	_, err = LoadZoneSpec(strings.NewReader(`{"zone": "example.com.", "records": [{"name": "www", "type": "A", "value": "203.0.113.1"}]}`))
+	assert.NoError(t, err, "JSON specs must be accepted")
+
+	_, err = LoadZoneSpec(strings.NewReader("zone: example.com.\nrecrods: []\n"))
+	assert.Error(t, err, "unknown fields must be rejected")
+}
+
+func TestPlanAndApplySync(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.",
+		DNSRecord{ID: 1, Type: "A", Ipv4Address: "198.51.100.1", TTL: 300, State: true},
+		DNSRecord{ID: 2, Type: "A", NodeName: "www", Ipv4Address: "198.51.100.1", TTL: 300, State: true},
+		DNSRecord{ID: 3, Type: "TXT", NodeName: "unmanaged", TextData: "keep", TTL: 300, State: true},
+	)
+
+	spec, err := LoadZoneSpec(strings.NewReader(testZoneSpec))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	plan, err := provider.PlanSync(context.TODO(), spec)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, `~ @ A "198.51.100.1" (ttl 5m0s) => "203.0.113.1" (ttl 5m0s)
++ www CNAME "example.com" (ttl 5m0s)
++ api AAAA "2001:db8::1" (ttl 1m0s)
+- www A "198.51.100.1" (ttl 5m0s)
+zone example.com.: 2 to create, 1 to update, 1 to delete
+`, plan.String())
+
+	result, err := provider.ApplySync(context.TODO(), plan)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, result.Applied, 4)
+
+	plan, err = provider.PlanSync(context.TODO(), spec)
+	if assert.NoError(t, err) {
+		assert.Empty(t, plan.Changes)
+		assert.Equal(t, "zone example.com. is up to date\n", plan.String())
+	}
+
+	records := fake.Records()
+	assert.Len(t, records, 4)
+	assert.Equal(t, "keep", records[1].TextData, "unmanaged records must be kept")
+}
+
+func TestApplySyncReportsPartialFailure(t *testing.T) {
+	provider, fake := newFakeProvider(t, "example.com.")
+	fake.fail("POST", "/dns/100/record", 1)
+
+	spec, err := LoadZoneSpec(strings.NewReader(testZoneSpec))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	plan, err := provider.PlanSync(context.TODO(), spec)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	result, err := provider.ApplySync(context.TODO(), plan)
+	assert.Error(t, err)
+	assert.Len(t, result.Applied, 2)
+	if assert.Len(t, result.Failed, 1) {
+		assert.Equal(t, SyncCreate, result.Failed[0].Change.Action)
+		assert.Contains(t, result.Failed[0].Err.Error(), "injected failure")
+	}
+}